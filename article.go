@@ -5,45 +5,42 @@
 package diffbot
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 )
 
 // Article represents an clean article text.
 //
 // See http://diffbot.com/dev/docs/article/
 type Article struct {
-	Url           string                 `json:"url"`
-	ResolvedUrl   string                 `json:"resolved_url"`
-	Icon          string                 `json:"icon"`
-	Meta          map[string]interface{} `json:"meta,omitempty"`        // Returned with fields.
-	QueryString   string                 `json:"querystring,omitempty"` // Returned with fields.
-	Links         []string               `json:"links,omitempty"`       // Returned with fields.
-	Type          string                 `json:"type"`
-	Title         string                 `json:"title"`
-	Text          string                 `json:"text"`
-	Html          string                 `json:"html"`
-	NumPages      string                 `json:"numPages"`
-	Date          string                 `json:"date"`
-	Author        string                 `json:"author"`
-	Tags          []string               `json:"tags,omitempty"`          // Returned with fields.
-	HumanLanguage string                 `json:"humanLanguage,omitempty"` // Returned with fields.
-	Images        []struct {
-		Url         string `json:"url"`
-		PixelHeight int    `json:"pixelHeight"`
-		PixelWidth  int    `json:"pixelWidth"`
-		Caption     string `json:"caption"`
-		Primary     string `json:"primary"`
-	} `json:"images"`
-	Videos []struct {
-		Url         string `json:"url"`
-		PixelHeight int    `json:"pixelHeight"`
-		PixelWidth  int    `json:"pixelWidth"`
-		Primary     string `json:"primary"`
-	} `json:"videos"`
+	Url           string                     `json:"url"`
+	ResolvedUrl   string                     `json:"resolved_url"`
+	Icon          string                     `json:"icon"`
+	Meta          map[string]interface{}     `json:"meta,omitempty"`        // Returned with fields.
+	QueryString   string                     `json:"querystring,omitempty"` // Returned with fields.
+	Links         []string                   `json:"links,omitempty"`       // Returned with fields.
+	Type          string                     `json:"type"`
+	Title         string                     `json:"title"`
+	Text          string                     `json:"text"`
+	Html          string                     `json:"html"`
+	NumPages      string                     `json:"numPages"`
+	Date          string                     `json:"date"`
+	Author        string                     `json:"author"`
+	Tags          []string                   `json:"tags,omitempty"`          // Returned with fields.
+	HumanLanguage string                     `json:"humanLanguage,omitempty"` // Returned with fields.
+	Images        []ArticleImage             `json:"images"`
+	Videos        []ArticleVideo             `json:"videos"`
+	NextPage      string                     `json:"nextPage,omitempty"`  // Link to the next page of a paginated article.
+	NextPages     []string                   `json:"nextPages,omitempty"` // Links to all the next pages of a paginated article.
+	Pages         []ArticlePage              `json:"pages,omitempty"`     // Page boundaries of a stitched article, see Options.ArticleAllPages.
+	Warnings      []DecodeWarning            `json:"-"`                   // Mismatched fields, see ParseJson.
+	Raw           json.RawMessage            `json:"-"`                   // The JSON data, see ParseJson.
+	Extra         map[string]json.RawMessage `json:"-"`                   // Unrecognised JSON fields, see ParseJson.
 }
 
-// type of Article.Images[?]
-type articleImageType struct {
+// ArticleImage represents an image of the Article.
+type ArticleImage struct {
 	Url         string `json:"url"`
 	PixelHeight int    `json:"pixelHeight"`
 	PixelWidth  int    `json:"pixelWidth"`
@@ -51,14 +48,24 @@ type articleImageType struct {
 	Primary     string `json:"primary"`
 }
 
-// type of Article.Videos[?]
-type articleVideoType struct {
+// ArticleVideo represents a video of the Article.
+type ArticleVideo struct {
 	Url         string `json:"url"`
 	PixelHeight int    `json:"pixelHeight"`
 	PixelWidth  int    `json:"pixelWidth"`
 	Primary     string `json:"primary"`
 }
 
+// IsPrimary parses the Primary field.
+func (p *ArticleImage) IsPrimary() bool {
+	return ParseBool(p.Primary)
+}
+
+// IsPrimary parses the Primary field.
+func (p *ArticleVideo) IsPrimary() bool {
+	return ParseBool(p.Primary)
+}
+
 // ParseArticle parse the clean article text from news article web pages.
 //
 // Request
@@ -163,18 +170,84 @@ type articleVideoType struct {
 //
 // See http://diffbot.com/dev/docs/article/.
 //
+// If opt.ArticleAllPages is true, the next pages of a paginated article
+// are fetched and stitched into the result, see ParseArticleContext.
+//
 func ParseArticle(token, url string, opt *Options) (*Article, error) {
-	body, err := Diffbot("article", token, url, opt)
+	return ParseArticleContext(context.Background(), token, url, opt)
+}
+
+// ParseArticleContext like ParseArticle function, but carries a context.
+//
+// If opt.ArticleAllPages is true, the pages listed in Article.NextPages are
+// fetched concurrently (at most opt.ArticlePageConcurrency at a time), or
+// the Article.NextPage links are followed one by one, up to opt.ArticleMaxPages
+// pages. The text, html, images and videos of the pages are merged into the
+// first page, and the page boundaries are recorded in Article.Pages.
+func ParseArticleContext(ctx context.Context, token, url string, opt *Options) (*Article, error) {
+	if opt != nil && opt.ArticleAllPages {
+		return parseArticlePages(ctx, token, url, opt)
+	}
+	body, err := DiffbotContext(ctx, "article", token, url, opt)
 	if err != nil {
 		return nil, err
 	}
 	var result Article
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := opt.parseJson(ctx, "article", body, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
+// ParseJson parses the JSON-encoded Article data, see DecodeWarning. The
+// unknown fields are errors only in ParseArticle with Options.StrictFields.
+func (p *Article) ParseJson(data []byte) (err error) {
+	p.Warnings, p.Raw, p.Extra, err = decodeResultJson(data, p)
+	return err
+}
+
+// DateTime parses the Date field, see ParseDate.
+func (p *Article) DateTime() (time.Time, error) {
+	return ParseDate(p.Date)
+}
+
+// PageCount parses the NumPages field.
+// It returns 1 if NumPages is empty or invalid.
+func (p *Article) PageCount() int {
+	if n, err := ParseInt(p.NumPages); err == nil && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// PrimaryImage returns the primary image, or the first image if no image
+// is marked as primary. It returns nil if the article has no images.
+func (p *Article) PrimaryImage() *ArticleImage {
+	for i := range p.Images {
+		if p.Images[i].IsPrimary() {
+			return &p.Images[i]
+		}
+	}
+	if len(p.Images) != 0 {
+		return &p.Images[0]
+	}
+	return nil
+}
+
+// PrimaryVideo returns the primary video, or the first video if no video
+// is marked as primary. It returns nil if the article has no videos.
+func (p *Article) PrimaryVideo() *ArticleVideo {
+	for i := range p.Videos {
+		if p.Videos[i].IsPrimary() {
+			return &p.Videos[i]
+		}
+	}
+	if len(p.Videos) != 0 {
+		return &p.Videos[0]
+	}
+	return nil
+}
+
 func (p *Article) String() string {
 	d, _ := json.Marshal(p)
 	return string(d)
@@ -0,0 +1,808 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	urlPkg "net/url"
+	"strconv"
+	"strings"
+)
+
+// SanitizePolicy is an allowlist of the HTML elements and attributes
+// kept by Article.SanitizedHTML.
+type SanitizePolicy struct {
+	// Elements maps the allowed elements to their allowed attributes.
+	// The other elements are removed, but their children are kept.
+	Elements map[string][]string
+
+	// DropElements are removed with their children, e.g. script.
+	DropElements []string
+
+	// URLAttributes are resolved against the article URL, and removed
+	// if their scheme is not in URLSchemes.
+	URLAttributes []string
+	URLSchemes    []string
+
+	// RewriteURL is called with the resolved URL of the URL attributes,
+	// if not nil. An empty result removes the attribute.
+	RewriteURL func(tag, attr, url string) string
+}
+
+// DefaultSanitizePolicy returns a policy which keeps the text formatting,
+// links and images, and removes the scripts, styles, forms and embeds.
+func DefaultSanitizePolicy() *SanitizePolicy {
+	return &SanitizePolicy{
+		Elements: map[string][]string{
+			"a":          {"href", "title"},
+			"abbr":       {"title"},
+			"b":          nil,
+			"blockquote": {"cite"},
+			"br":         nil,
+			"caption":    nil,
+			"code":       nil,
+			"dd":         nil,
+			"del":        nil,
+			"dl":         nil,
+			"dt":         nil,
+			"em":         nil,
+			"figcaption": nil,
+			"figure":     nil,
+			"h1":         nil,
+			"h2":         nil,
+			"h3":         nil,
+			"h4":         nil,
+			"h5":         nil,
+			"h6":         nil,
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"src", "alt", "title", "width", "height"},
+			"ins":        nil,
+			"li":         nil,
+			"ol":         nil,
+			"p":          nil,
+			"pre":        nil,
+			"q":          {"cite"},
+			"s":          nil,
+			"small":      nil,
+			"strong":     nil,
+			"sub":        nil,
+			"sup":        nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"colspan", "rowspan"},
+			"tfoot":      nil,
+			"th":         {"colspan", "rowspan"},
+			"thead":      nil,
+			"tr":         nil,
+			"u":          nil,
+			"ul":         nil,
+		},
+		DropElements: []string{
+			"script", "style", "noscript", "template", "head", "title",
+			"iframe", "frame", "frameset", "object", "embed", "applet",
+			"form", "input", "button", "select", "textarea", "svg", "math",
+		},
+		URLAttributes: []string{"href", "src", "cite"},
+		URLSchemes:    []string{"http", "https", "mailto"},
+	}
+}
+
+// SanitizedHTML returns the Html field filtered by the policy, the relative
+// links are resolved against the ResolvedUrl (or Url) field.
+//
+// If policy is nil, the DefaultSanitizePolicy is used. The images without
+// a valid src are removed. The result is well-formed, so it is also valid
+// XHTML content.
+func (p *Article) SanitizedHTML(policy *SanitizePolicy) string {
+	return sanitizeHTML(p.Html, p.baseUrl(), policy)
+}
+
+// sanitizeHTML filters the HTML fragment by the policy, or by the
+// DefaultSanitizePolicy if nil, see Article.SanitizedHTML.
+func sanitizeHTML(s string, base *urlPkg.URL, policy *SanitizePolicy) string {
+	if policy == nil {
+		policy = DefaultSanitizePolicy()
+	}
+	root := parseHtmlFragment(s)
+	var buf strings.Builder
+	policy.render(&buf, root, base)
+	return buf.String()
+}
+
+// Markdown converts the Html field to Markdown, the relative links are
+// resolved against the ResolvedUrl (or Url) field.
+//
+// The Html is sanitized with the DefaultSanitizePolicy first.
+func (p *Article) Markdown() string {
+	policy := DefaultSanitizePolicy()
+	root := parseHtmlFragment(p.Html)
+	policy.filter(root, p.baseUrl())
+
+	m := &markdownWriter{}
+	m.blocks(root.children)
+	return m.String()
+}
+
+func (p *Article) baseUrl() *urlPkg.URL {
+	for _, s := range []string{p.ResolvedUrl, p.Url} {
+		if u, err := urlPkg.Parse(s); err == nil && u.IsAbs() {
+			return u
+		}
+	}
+	return nil
+}
+
+// htmlNode is an element or a text node of a parsed HTML fragment.
+type htmlNode struct {
+	tag      string // empty for the text node
+	attrs    []xml.Attr
+	text     string
+	children []*htmlNode
+}
+
+func (p *htmlNode) attr(name string) string {
+	for _, attr := range p.attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// parseHtmlFragment parses the HTML leniently, like a browser does but
+// without the full HTML5 tree construction:
+//
+//   - a "<" which does not start a tag is text, e.g. "1 < 2",
+//   - the text of script, style, textarea and title is read up to their
+//     end tag, so a "<" in a script is not a tag,
+//   - an end tag without an open element is ignored,
+//   - the p, li, dt, dd, tr, td and th elements are closed implicitly,
+//     e.g. "<p>a<p>b" is "<p>a</p><p>b</p>",
+//   - an incomplete tag at the end is dropped.
+//
+// The result is a tree, so it is rendered as well-formed markup.
+func parseHtmlFragment(s string) *htmlNode {
+	root := &htmlNode{tag: "#root"}
+	b := &htmlTreeBuilder{stack: []*htmlNode{root}}
+	z := &htmlTokenizer{s: s}
+	for {
+		t, ok := z.next()
+		if !ok {
+			break
+		}
+		switch t.kind {
+		case htmlTextToken:
+			b.text(t.text)
+		case htmlStartTagToken:
+			b.start(t)
+		case htmlEndTagToken:
+			b.end(t.tag)
+		}
+	}
+	return root
+}
+
+type htmlTokenKind int
+
+const (
+	htmlTextToken htmlTokenKind = iota
+	htmlStartTagToken
+	htmlEndTagToken
+)
+
+type htmlToken struct {
+	kind        htmlTokenKind
+	tag         string // lower case
+	attrs       []xml.Attr
+	selfClosing bool
+	text        string // unescaped
+}
+
+// htmlRawTextElements hold text up to their end tag, the value reports
+// whether the character references of the text are unescaped.
+var htmlRawTextElements = map[string]bool{
+	"script": false, "style": false, "xmp": false, "iframe": false,
+	"noembed": false, "noframes": false, "noscript": false,
+	"textarea": true, "title": true,
+}
+
+// htmlTokenizer splits the HTML into text, start and end tag tokens,
+// the comments and doctypes are skipped.
+type htmlTokenizer struct {
+	s   string
+	pos int
+	raw string // the open raw text element
+}
+
+func (p *htmlTokenizer) next() (htmlToken, bool) {
+	for p.pos < len(p.s) {
+		if p.raw != "" {
+			return p.rawText(), true
+		}
+		rest := p.s[p.pos:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			p.until("-->", 4)
+		case strings.HasPrefix(rest, "<![CDATA["):
+			return htmlToken{text: p.until("]]>", 9)}, true
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			p.until(">", 2)
+		case len(rest) > 2 && rest[0] == '<' && rest[1] == '/' && isAsciiLetter(rest[2]):
+			return p.tag(htmlEndTagToken)
+		case len(rest) > 1 && rest[0] == '<' && isAsciiLetter(rest[1]):
+			return p.tag(htmlStartTagToken)
+		default:
+			return p.text(), true
+		}
+	}
+	return htmlToken{}, false
+}
+
+// until skips n bytes, and returns the text up to the end marker,
+// which is skipped too.
+func (p *htmlTokenizer) until(end string, n int) string {
+	rest := p.s[p.pos+n:]
+	i := strings.Index(rest, end)
+	if i < 0 {
+		p.pos = len(p.s)
+		return rest
+	}
+	p.pos += n + i + len(end)
+	return rest[:i]
+}
+
+// text reads the text up to the next "<", the first byte may be a "<"
+// which does not start a tag.
+func (p *htmlTokenizer) text() htmlToken {
+	end := len(p.s)
+	if i := strings.IndexByte(p.s[p.pos+1:], '<'); i >= 0 {
+		end = p.pos + 1 + i
+	}
+	text := p.s[p.pos:end]
+	p.pos = end
+	return htmlToken{text: html.UnescapeString(text)}
+}
+
+// rawText reads the text of the raw text element up to its end tag.
+func (p *htmlTokenizer) rawText() htmlToken {
+	rest := p.s[p.pos:]
+	end := len(rest)
+	for i := strings.Index(rest, "</"); i >= 0; {
+		tail := rest[i+2:]
+		if len(tail) >= len(p.raw) && strings.EqualFold(tail[:len(p.raw)], p.raw) &&
+			(len(tail) == len(p.raw) || isHtmlTagEnd(tail[len(p.raw)])) {
+			end = i
+			break
+		}
+		j := strings.Index(rest[i+2:], "</")
+		if j < 0 {
+			break
+		}
+		i += 2 + j
+	}
+	text := rest[:end]
+	if htmlRawTextElements[p.raw] {
+		text = html.UnescapeString(text)
+	}
+	p.pos += end
+	p.raw = ""
+	return htmlToken{text: text}
+}
+
+// tag reads a start or end tag, the attributes of the end tags are ignored.
+// It returns false if the tag is not complete.
+func (p *htmlTokenizer) tag(kind htmlTokenKind) (htmlToken, bool) {
+	s := p.s
+	i := p.pos + 1
+	if kind == htmlEndTagToken {
+		i++
+	}
+	start := i
+	for i < len(s) && !isHtmlTagEnd(s[i]) {
+		i++
+	}
+	t := htmlToken{kind: kind, tag: strings.ToLower(s[start:i])}
+	for {
+		for i < len(s) && isHtmlSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			p.pos = len(s)
+			return htmlToken{}, false
+		}
+		if s[i] == '>' {
+			i++
+			break
+		}
+		if s[i] == '/' {
+			if i++; i < len(s) && s[i] == '>' {
+				t.selfClosing = true
+				i++
+				break
+			}
+			continue
+		}
+
+		// The name of an attribute may begin with "=".
+		start := i
+		for i++; i < len(s) && !isHtmlTagEnd(s[i]) && s[i] != '='; i++ {
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isHtmlSpace(s[i]) {
+			i++
+		}
+		var value string
+		if i < len(s) && s[i] == '=' {
+			for i++; i < len(s) && isHtmlSpace(s[i]); i++ {
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				j := strings.IndexByte(s[i+1:], s[i])
+				if j < 0 {
+					p.pos = len(s)
+					return htmlToken{}, false
+				}
+				value, i = s[i+1:i+1+j], i+2+j
+			} else {
+				start := i
+				for i < len(s) && !isHtmlSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if kind == htmlStartTagToken && !hasHtmlAttr(t.attrs, name) {
+			t.attrs = append(t.attrs, xml.Attr{Name: xml.Name{Local: name}, Value: html.UnescapeString(value)})
+		}
+	}
+	p.pos = i
+	if _, ok := htmlRawTextElements[t.tag]; ok && kind == htmlStartTagToken && !t.selfClosing {
+		p.raw = t.tag
+	}
+	return t, true
+}
+
+func hasHtmlAttr(attrs []xml.Attr, name string) bool {
+	for _, attr := range attrs {
+		if attr.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isAsciiLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isHtmlSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isHtmlTagEnd(c byte) bool {
+	return isHtmlSpace(c) || c == '/' || c == '>'
+}
+
+// htmlTreeBuilder builds the tree of the tokens, the stack holds the
+// open elements.
+type htmlTreeBuilder struct {
+	stack []*htmlNode
+}
+
+func (p *htmlTreeBuilder) text(s string) {
+	if s == "" {
+		return
+	}
+	top := p.stack[len(p.stack)-1]
+	if n := len(top.children); n != 0 && top.children[n-1].tag == "" {
+		top.children[n-1].text += s
+		return
+	}
+	top.children = append(top.children, &htmlNode{text: s})
+}
+
+func (p *htmlTreeBuilder) start(t htmlToken) {
+	p.impliedEnd(t.tag)
+	node := &htmlNode{tag: t.tag, attrs: t.attrs}
+	top := p.stack[len(p.stack)-1]
+	top.children = append(top.children, node)
+	if !htmlVoidElements[t.tag] && !t.selfClosing {
+		p.stack = append(p.stack, node)
+	}
+}
+
+// end closes the open element, the end tag is ignored if there is no
+// such element in the scope.
+func (p *htmlTreeBuilder) end(tag string) {
+	p.close([]string{tag}, htmlScopeElements)
+}
+
+// htmlScopeElements stop the search for the open elements to close, e.g.
+// a p outside of a table is not closed by the elements in the table.
+var htmlScopeElements = []string{
+	"applet", "button", "caption", "marquee", "object", "table", "td", "template", "th",
+}
+
+var htmlClosePElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true,
+	"details": true, "dialog": true, "dir": true, "div": true, "dl": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true,
+	"main": true, "menu": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "summary": true, "table": true, "ul": true, "li": true, "dd": true, "dt": true,
+}
+
+// impliedEnd closes the open elements which are ended by the start tag.
+func (p *htmlTreeBuilder) impliedEnd(tag string) {
+	if htmlClosePElements[tag] {
+		p.close([]string{"p"}, htmlScopeElements)
+	}
+	switch tag {
+	case "li":
+		p.close([]string{"li"}, append([]string{"ol", "ul"}, htmlScopeElements...))
+	case "dt", "dd":
+		p.close([]string{"dt", "dd"}, append([]string{"dl"}, htmlScopeElements...))
+	case "thead", "tbody", "tfoot":
+		p.close([]string{"thead", "tbody", "tfoot"}, []string{"table"})
+	case "tr":
+		p.close([]string{"tr"}, []string{"table", "thead", "tbody", "tfoot"})
+	case "td", "th":
+		p.close([]string{"td", "th"}, []string{"tr", "table"})
+	}
+}
+
+// close pops the innermost open element of targets and the elements in it,
+// unless one of boundaries is found first.
+func (p *htmlTreeBuilder) close(targets, boundaries []string) {
+	for i := len(p.stack) - 1; i > 0; i-- {
+		tag := p.stack[i].tag
+		if containsString(targets, tag) {
+			p.stack = p.stack[:i]
+			return
+		}
+		if containsString(boundaries, tag) {
+			return
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SanitizePolicy) dropped(tag string) bool {
+	return containsString(p.DropElements, tag)
+}
+
+func (p *SanitizePolicy) isUrlAttr(name string) bool {
+	return containsString(p.URLAttributes, name)
+}
+
+// filter removes the disallowed nodes and attributes in place.
+func (p *SanitizePolicy) filter(node *htmlNode, base *urlPkg.URL) {
+	var children []*htmlNode
+	for _, child := range node.children {
+		if child.tag == "" {
+			children = append(children, child)
+			continue
+		}
+		if p.dropped(child.tag) {
+			continue
+		}
+		p.filter(child, base)
+		allowed, ok := p.Elements[child.tag]
+		if !ok {
+			children = append(children, child.children...)
+			continue
+		}
+		child.attrs = p.filterAttrs(child.tag, child.attrs, allowed, base)
+		if child.tag == "img" && child.attr("src") == "" {
+			continue
+		}
+		children = append(children, child)
+	}
+	node.children = children
+}
+
+func (p *SanitizePolicy) filterAttrs(tag string, attrs []xml.Attr, allowed []string, base *urlPkg.URL) []xml.Attr {
+	var result []xml.Attr
+	for _, attr := range attrs {
+		var ok bool
+		for _, name := range allowed {
+			if attr.Name.Local == name {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if p.isUrlAttr(attr.Name.Local) {
+			if attr.Value, ok = p.resolveUrl(attr.Value, base); !ok {
+				continue
+			}
+			if p.RewriteURL != nil {
+				if attr.Value = p.RewriteURL(tag, attr.Name.Local, attr.Value); attr.Value == "" {
+					continue
+				}
+			}
+		}
+		result = append(result, xml.Attr{Name: xml.Name{Local: attr.Name.Local}, Value: attr.Value})
+	}
+	return result
+}
+
+func (p *SanitizePolicy) resolveUrl(s string, base *urlPkg.URL) (string, bool) {
+	u, err := urlPkg.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme == "" {
+		// A relative link without base, or a fragment.
+		return u.String(), u.Opaque == ""
+	}
+	for _, scheme := range p.URLSchemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return u.String(), true
+		}
+	}
+	return "", false
+}
+
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true, "wbr": true,
+}
+
+func (p *SanitizePolicy) render(w io.Writer, root *htmlNode, base *urlPkg.URL) {
+	p.filter(root, base)
+	writeHtmlNodes(w, root.children)
+}
+
+func writeHtmlNodes(w io.Writer, nodes []*htmlNode) {
+	for _, node := range nodes {
+		if node.tag == "" {
+			io.WriteString(w, html.EscapeString(node.text))
+			continue
+		}
+		io.WriteString(w, "<"+node.tag)
+		for _, attr := range node.attrs {
+			io.WriteString(w, " "+attr.Name.Local+`="`+html.EscapeString(attr.Value)+`"`)
+		}
+		if htmlVoidElements[node.tag] {
+			io.WriteString(w, "/>")
+			continue
+		}
+		io.WriteString(w, ">")
+		writeHtmlNodes(w, node.children)
+		io.WriteString(w, "</"+node.tag+">")
+	}
+}
+
+// markdownWriter renders the sanitized nodes as Markdown.
+type markdownWriter struct {
+	buf    strings.Builder
+	prefix string // e.g. "> " in blockquote, or the list indent
+	list   []string
+	index  []int
+}
+
+func (p *markdownWriter) String() string {
+	lines := strings.Split(p.buf.String(), "\n")
+	var result []string
+	blank := true
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(strings.Replace(line, ">", "", -1)) == "" {
+			if !blank {
+				result = append(result, "")
+			}
+			blank = true
+			continue
+		}
+		result = append(result, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(result, "\n")) + "\n"
+}
+
+// block writes a block separated by blank lines.
+func (p *markdownWriter) block(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	p.buf.WriteString("\n")
+	for _, line := range strings.Split(s, "\n") {
+		p.buf.WriteString(p.prefix + line + "\n")
+	}
+	p.buf.WriteString("\n")
+}
+
+func (p *markdownWriter) blocks(nodes []*htmlNode) {
+	var inline []*htmlNode
+	flush := func() {
+		p.block(markdownInline(inline))
+		inline = nil
+	}
+	for _, node := range nodes {
+		if node.tag == "" || !markdownBlockElements[node.tag] {
+			inline = append(inline, node)
+			continue
+		}
+		flush()
+		p.element(node)
+	}
+	flush()
+}
+
+var markdownBlockElements = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "blockquote": true, "pre": true, "hr": true,
+	"figure": true, "figcaption": true, "table": true, "thead": true, "tbody": true, "tfoot": true,
+	"tr": true, "dl": true, "dt": true, "dd": true, "caption": true,
+}
+
+func (p *markdownWriter) element(node *htmlNode) {
+	switch node.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(node.tag[1:])
+		p.block(strings.Repeat("#", level) + " " + markdownInline(node.children))
+	case "hr":
+		p.block("---")
+	case "pre":
+		p.block("```\n" + strings.Trim(htmlText(node), "\n") + "\n```")
+	case "blockquote":
+		prefix := p.prefix
+		p.prefix += "> "
+		p.blocks(node.children)
+		p.prefix = prefix
+	case "ul", "ol":
+		p.list = append(p.list, node.tag)
+		p.index = append(p.index, 0)
+		for _, child := range node.children {
+			if child.tag == "li" {
+				p.listItem(child)
+			} else if child.tag != "" {
+				p.element(child)
+			}
+		}
+		p.list = p.list[:len(p.list)-1]
+		p.index = p.index[:len(p.index)-1]
+	case "li":
+		p.listItem(node)
+	default:
+		p.blocks(node.children)
+	}
+}
+
+func (p *markdownWriter) listItem(node *htmlNode) {
+	marker := "- "
+	if n := len(p.list); n != 0 {
+		p.index[n-1]++
+		if p.list[n-1] == "ol" {
+			marker = strconv.Itoa(p.index[n-1]) + ". "
+		}
+	}
+
+	sub := &markdownWriter{list: p.list, index: p.index}
+	sub.blocks(node.children)
+	lines := strings.Split(strings.TrimSpace(sub.buf.String()), "\n")
+	indent := strings.Repeat(" ", len(marker))
+
+	p.buf.WriteString(p.prefix + marker + lines[0] + "\n")
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p.buf.WriteString(p.prefix + indent + line + "\n")
+	}
+}
+
+// markdownInline renders the inline nodes on a single line,
+// except the hard line breaks.
+func markdownInline(nodes []*htmlNode) string {
+	var buf strings.Builder
+	for _, node := range nodes {
+		switch node.tag {
+		case "":
+			buf.WriteString(markdownEscape(collapseSpace(node.text)))
+		case "br":
+			buf.WriteString("\\\n")
+		case "strong", "b":
+			buf.WriteString(markdownWrap("**", markdownInline(node.children)))
+		case "em", "i":
+			buf.WriteString(markdownWrap("*", markdownInline(node.children)))
+		case "del", "s":
+			buf.WriteString(markdownWrap("~~", markdownInline(node.children)))
+		case "code":
+			buf.WriteString(markdownWrap("`", htmlText(node)))
+		case "a":
+			text := markdownInline(node.children)
+			if href := node.attr("href"); href != "" {
+				buf.WriteString("[" + text + "](" + markdownUrl(href) + ")")
+			} else {
+				buf.WriteString(text)
+			}
+		case "img":
+			if src := node.attr("src"); src != "" {
+				buf.WriteString("![" + markdownEscape(node.attr("alt")) + "](" + markdownUrl(src) + ")")
+			}
+		default:
+			buf.WriteString(markdownInline(node.children))
+		}
+	}
+	return buf.String()
+}
+
+// markdownWrap wraps s with the marker, keeping the spaces outside.
+func markdownWrap(marker, s string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	i := strings.Index(s, trimmed)
+	return s[:i] + marker + trimmed + marker + s[i+len(trimmed):]
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func markdownUrl(s string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(s)
+}
+
+// collapseSpace replaces the runs of ASCII spaces with a single space.
+// The no-break spaces are kept.
+func collapseSpace(s string) string {
+	var buf strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			buf.WriteByte(' ')
+			space = false
+		}
+		buf.WriteRune(r)
+	}
+	if space {
+		buf.WriteByte(' ')
+	}
+	return buf.String()
+}
+
+// htmlText returns the text content of the node.
+func htmlText(node *htmlNode) string {
+	if node.tag == "" {
+		return node.text
+	}
+	var buf strings.Builder
+	for _, child := range node.children {
+		if child.tag == "br" {
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString(htmlText(child))
+	}
+	return buf.String()
+}
@@ -0,0 +1,187 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestArticle_markdown(t *testing.T) {
+	var article Article
+	if err := json.Unmarshal([]byte(testJsonDataArticle), &article); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := article.Markdown(), testMarkdownArticle; a != b {
+		t.Fatalf("expect = %q, got = %q", b, a)
+	}
+
+	article = Article{Url: "http://example.com/a/b.html", Html: testHtmlArticleMisc}
+	if a, b := article.Markdown(), testMarkdownArticleMisc; a != b {
+		t.Fatalf("expect = %q, got = %q", b, a)
+	}
+}
+
+func TestArticle_sanitizedHTML(t *testing.T) {
+	var article Article
+	if err := json.Unmarshal([]byte(testJsonDataArticle), &article); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := strings.TrimSpace(article.SanitizedHTML(nil)), testSanitizedHtmlArticle; a != b {
+		t.Fatalf("expect = %q, got = %q", b, a)
+	}
+
+	article = Article{Url: "http://example.com/a/b.html", Html: testHtmlArticleMisc}
+	if a, b := article.SanitizedHTML(nil), testSanitizedHtmlArticleMisc; a != b {
+		t.Fatalf("expect = %q, got = %q", b, a)
+	}
+
+	// ResolvedUrl takes precedence over Url.
+	article = Article{
+		Url:         "http://example.com/a/b.html",
+		ResolvedUrl: "https://www.example.com/x/y.html",
+		Html:        `<a href="z.html">z</a>`,
+	}
+	if a, b := article.SanitizedHTML(nil), `<a href="https://www.example.com/x/z.html">z</a>`; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestArticle_sanitizedHTMLPolicy(t *testing.T) {
+	article := Article{Html: `<p class="x">Hi <a href="http://a.com/" rel="nofollow">a</a> <img src="ftp://a.com/1.png"></p>`}
+	policy := &SanitizePolicy{
+		Elements: map[string][]string{
+			"p": {"class"},
+			"a": {"href", "rel"},
+		},
+		URLAttributes: []string{"href", "src"},
+		URLSchemes:    []string{"https"},
+	}
+	if a, b := article.SanitizedHTML(policy), `<p class="x">Hi <a rel="nofollow">a</a> </p>`; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	article = Article{
+		Url:  "http://a.com/",
+		Html: `<img src="1.png"><img src="2.png" alt="2"><a href="x.html">x</a>`,
+	}
+	policy = DefaultSanitizePolicy()
+	policy.RewriteURL = func(tag, attr, url string) string {
+		if tag == "img" && url == "http://a.com/1.png" {
+			return "images/1.png"
+		}
+		if tag == "img" {
+			return ""
+		}
+		return url
+	}
+	if a, b := article.SanitizedHTML(policy), `<img src="images/1.png"/><a href="http://a.com/x.html">x</a>`; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestArticle_sanitizedHTMLMalformed(t *testing.T) {
+	for i, v := range []struct {
+		html   string
+		expect string
+	}{
+		// An unescaped "<" is text.
+		{`<p>1 < 2 and more</p><p>second paragraph</p>`, `<p>1 &lt; 2 and more</p><p>second paragraph</p>`},
+		{`<p>a <3 b</p><p>c</p>`, `<p>a &lt;3 b</p><p>c</p>`},
+		{`<p>x</p><`, `<p>x</p>&lt;`},
+		// A "/" in the text is not an end tag.
+		{`<p>A/B testing is fun.</p><p>Next paragraph.</p>`, `<p>A/B testing is fun.</p><p>Next paragraph.</p>`},
+		{`<ul><li>I/O bound</li></ul>`, `<ul><li>I/O bound</li></ul>`},
+		{`<p>w/o sugar</p>`, `<p>w/o sugar</p>`},
+		// The inline scripts and styles are skipped as a whole.
+		{`<p>a</p><script>if (a<b) alert(1)</script><p>b</p>`, `<p>a</p><p>b</p>`},
+		{`<script>document.write("</p><p>")</SCRIPT ><p>b</p>`, `<p>b</p>`},
+		{`<style>a > b { color: red }</style><p>c</p>`, `<p>c</p>`},
+		// The stray end tags are ignored.
+		{`<p>a</p></div><p>b</p>`, `<p>a</p><p>b</p>`},
+		{`</p>a<b>b</i></b>c`, `a<b>b</b>c`},
+		// The p, li and cells are closed implicitly.
+		{`<p>a<p>b`, `<p>a</p><p>b</p>`},
+		{`<p>a<ul><li>b<li>c</ul>d`, `<p>a</p><ul><li>b</li><li>c</li></ul>d`},
+		{`<ol><li>a<ul><li>b</ul><li>c</ol>`, `<ol><li>a<ul><li>b</li></ul></li><li>c</li></ol>`},
+		{`<dl><dt>a<dd>b<dt>c</dl>`, `<dl><dt>a</dt><dd>b</dd><dt>c</dt></dl>`},
+		{`<table><tr><td>a<td>b<tr><td>c</table>`, `<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>`},
+		{`<table><tr><td><p>a</div>b</td></tr></table>`, `<table><tr><td><p>ab</p></td></tr></table>`},
+		// The comments, attributes and incomplete tags.
+		{`<!-- <p>x</p> --><a title='a "b"' TITLE=c>d</a>`, `<a title="a &#34;b&#34;">d</a>`},
+		{`<p>a<br/>b<img src=x.png alt=y>c</p>`, `<p>a<br/>b<img src="x.png" alt="y"/>c</p>`},
+		{`<p>a</p><a href="x`, `<p>a</p>`},
+	} {
+		article := Article{Html: v.html}
+		if a, b := article.SanitizedHTML(nil), v.expect; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, b, a)
+		}
+	}
+
+	article := Article{Html: "<p>a<p>b <script>x<y</script>c"}
+	if a, b := article.Markdown(), "a\n\nb c\n"; a != b {
+		t.Fatalf("expect = %q, got = %q", b, a)
+	}
+}
+
+// The &nbsp; of testJsonDataArticle are kept as U+00A0 below.
+const testMarkdownArticle = `Diffbot’s human wranglers are proud today to announce the release of our newest product: an API for… products!
+
+The [Product API](http://www.diffbot.com/products/automatic/product) can be used for extracting clean, structured data from any e-commerce product page. It automatically makes available all the product data you’d expect: price, discount/savings amount, shipping cost, product description, any relevant product images, SKU and/or other product IDs.
+
+Even cooler: pair the Product API with [Crawlbot](http://www.diffbot.com/products/crawlbot), our intelligent site-spidering tool, and let Diffbot determine which pages are products, then automatically structure the entire catalog. Here’s a quick demonstration of Crawlbot at work:
+
+We’ve developed the Product API over the course of two years, building upon our core vision technology that’s extracted structured data from billions of web pages, and training our machine learning systems using data from tens of thousands of unique shopping sites. We can’t wait for you to try it out.
+
+What are you waiting for? Check out the [Product API documentation](http://www.diffbot.com/products/automatic/product) and dive on in! If you need a token, check out our [pricing and plans](http://www.diffbot.com/pricing) (including our Free plan).
+
+Questions? Hit us up at [support@diffbot.com](mailto:support@diffbot.com).
+`
+
+const testSanitizedHtmlArticle = `<p>Diffbot’s human wranglers are proud today to announce the release of our newest product: an API for… products!</p>
+<p>The <a href="http://www.diffbot.com/products/automatic/product" title="Diffbot&#39;s Product API">Product API</a> can be used for extracting clean, structured data from any e-commerce product page. It automatically makes available all the product data you’d expect: price, discount/savings amount, shipping cost, product description, any relevant product images, SKU and/or other product IDs.</p>
+
+<p>Even cooler: pair the Product API with <a href="http://www.diffbot.com/products/crawlbot" title="Crawlbot from Diffbot">Crawlbot</a>, our intelligent site-spidering tool, and let Diffbot determine which pages are products, then automatically structure the entire catalog. Here’s a quick demonstration of Crawlbot at work:</p>
+
+<p>We’ve developed the Product API over the course of two years, building upon our core vision technology that’s extracted structured data from billions of web pages, and training our machine learning systems using data from tens of thousands of unique shopping sites. We can’t wait for you to try it out.</p>
+<p>What are you waiting for? Check out the <a href="http://www.diffbot.com/products/automatic/product" title="Diffbot&#39;s Product API">Product API documentation</a> and dive on in! If you need a token, check out our <a href="http://www.diffbot.com/pricing">pricing and plans</a> (including our Free plan).</p>
+<p>Questions? Hit us up at <a href="mailto:support@diffbot.com">support@diffbot.com</a>.</p>`
+
+const testHtmlArticleMisc = `<h2 onclick="x()">Intro <em>text</em></h2>
+<script>alert(1)</script><style>p{}</style>
+<p>See <a href="../c.html">this *page*</a>, <a href="javascript:alert(1)">bad</a> and <img src="/img/1.png" alt="one">.<br>Next line</p>
+<ul><li>First</li><li>Second<ol><li>Nested</li></ol></li></ul>
+<blockquote><p>Quote</p></blockquote>
+<pre>a  b
+  c</pre>
+<span class="x">Loose <strong>bold</strong> <code>x_y</code></span>`
+
+const testMarkdownArticleMisc = "## Intro *text*\n" +
+	"\n" +
+	"See [this \\*page\\*](http://example.com/c.html), bad and ![one](http://example.com/img/1.png).\\\n" +
+	"Next line\n" +
+	"\n" +
+	"- First\n" +
+	"- Second\n" +
+	"  1. Nested\n" +
+	"\n" +
+	"> Quote\n" +
+	"\n" +
+	"```\n" +
+	"a  b\n" +
+	"  c\n" +
+	"```\n" +
+	"\n" +
+	"Loose **bold** `x_y`\n"
+
+const testSanitizedHtmlArticleMisc = `<h2>Intro <em>text</em></h2>
+
+<p>See <a href="http://example.com/c.html">this *page*</a>, <a>bad</a> and <img src="http://example.com/img/1.png" alt="one"/>.<br/>Next line</p>
+<ul><li>First</li><li>Second<ol><li>Nested</li></ol></li></ul>
+<blockquote><p>Quote</p></blockquote>
+<pre>a  b
+  c</pre>
+Loose <strong>bold</strong> <code>x_y</code>`
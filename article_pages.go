@@ -0,0 +1,173 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	defaultArticleMaxPages        = 20
+	defaultArticlePageConcurrency = 4
+)
+
+// ArticlePage records where a page starts in a stitched Article.
+type ArticlePage struct {
+	Url        string `json:"url"`
+	TextOffset int    `json:"textOffset"` // Byte offset in Article.Text
+	HtmlOffset int    `json:"htmlOffset"` // Byte offset in Article.Html
+}
+
+func (p *Options) articleMaxPages() int {
+	if p.ArticleMaxPages > 0 {
+		return p.ArticleMaxPages
+	}
+	return defaultArticleMaxPages
+}
+
+func (p *Options) articlePageConcurrency() int {
+	if p.ArticlePageConcurrency > 0 {
+		return p.ArticlePageConcurrency
+	}
+	return defaultArticlePageConcurrency
+}
+
+func parseArticlePages(ctx context.Context, token, url string, opt *Options) (*Article, error) {
+	pageOpt := *opt
+	pageOpt.ArticleAllPages = false
+
+	first, err := ParseArticleContext(ctx, token, url, &pageOpt)
+	if err != nil {
+		return nil, err
+	}
+	maxPages := opt.articleMaxPages()
+
+	seen := map[string]bool{url: true, first.Url: true, first.ResolvedUrl: true}
+	pages := []*Article{first}
+	urls := []string{url}
+
+	if len(first.NextPages) != 0 {
+		var next []string
+		for _, link := range first.NextPages {
+			if !seen[link] && len(urls)+len(next) < maxPages {
+				seen[link] = true
+				next = append(next, link)
+			}
+		}
+		rest, err := fetchArticlePages(ctx, token, next, &pageOpt, opt.articlePageConcurrency())
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, rest...)
+		urls = append(urls, next...)
+	} else {
+		for link := first.NextPage; link != "" && !seen[link] && len(pages) < maxPages; {
+			seen[link] = true
+			page, err := ParseArticleContext(ctx, token, link, &pageOpt)
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, page)
+			urls = append(urls, link)
+			link = page.NextPage
+		}
+	}
+
+	return mergeArticlePages(pages, urls), nil
+}
+
+// fetchArticlePages fetches the pages concurrently, the result keeps the order of urls.
+func fetchArticlePages(ctx context.Context, token string, urls []string, opt *Options, concurrency int) ([]*Article, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make([]*Article, len(urls))
+	errs := make([]error, len(urls))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, link := range urls {
+		wg.Add(1)
+		go func(i int, link string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			if pages[i], errs[i] = ParseArticleContext(ctx, token, link, opt); errs[i] != nil {
+				cancel()
+			}
+		}(i, link)
+	}
+	wg.Wait()
+
+	// Report the first failed page, not the cancellation it caused.
+	for i := range errs {
+		if errs[i] != nil && !errors.Is(errs[i], context.Canceled) {
+			return nil, errs[i]
+		}
+	}
+	for i := range errs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+	}
+	return pages, nil
+}
+
+// mergeArticlePages merges the pages into the first one.
+func mergeArticlePages(pages []*Article, urls []string) *Article {
+	result := pages[0]
+	result.Pages = []ArticlePage{{Url: urls[0]}}
+	if len(pages) == 1 {
+		return result
+	}
+
+	images := make(map[string]bool)
+	for _, img := range result.Images {
+		images[img.Url] = true
+	}
+	videos := make(map[string]bool)
+	for _, video := range result.Videos {
+		videos[video.Url] = true
+	}
+
+	for i, page := range pages[1:] {
+		if result.Text != "" && page.Text != "" {
+			result.Text += "\n\n"
+		}
+		if result.Html != "" && page.Html != "" {
+			result.Html += "\n"
+		}
+		result.Pages = append(result.Pages, ArticlePage{
+			Url:        urls[i+1],
+			TextOffset: len(result.Text),
+			HtmlOffset: len(result.Html),
+		})
+		result.Text += page.Text
+		result.Html += page.Html
+
+		for _, img := range page.Images {
+			if !images[img.Url] {
+				images[img.Url] = true
+				result.Images = append(result.Images, img)
+			}
+		}
+		for _, video := range page.Videos {
+			if !videos[video.Url] {
+				videos[video.Url] = true
+				result.Videos = append(result.Videos, video)
+			}
+		}
+		result.Warnings = append(result.Warnings, page.Warnings...)
+	}
+	result.NextPage = ""
+	return result
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseArticle_allPages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := testJsonDataArticlePages[r.URL.Query().Get("url")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"Could not download page (404)","errorCode":404}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	for _, url := range []string{"http://example.com/list/1", "http://example.com/chain/1"} {
+		opt := &Options{Client: testRedirectDoer(ts.URL), ArticleAllPages: true}
+		article, err := ParseArticle("token", url, opt)
+		if err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		if a, b := "Page one.\n\nPage two.\n\nPage three.", article.Text; a != b {
+			t.Fatalf("%s: expect = %q, got = %q", url, a, b)
+		}
+		if a, b := "<p>Page one.</p>\n<p>Page two.</p>\n<p>Page three.</p>", article.Html; a != b {
+			t.Fatalf("%s: expect = %q, got = %q", url, a, b)
+		}
+		if a, b := 3, len(article.Pages); a != b {
+			t.Fatalf("%s: expect = %v, got = %v", url, a, b)
+		}
+		for i, page := range article.Pages {
+			a := fmt.Sprintf("Page %s.", []string{"one", "two", "three"}[i])
+			if b := article.Text[page.TextOffset:][:len(a)]; a != b {
+				t.Fatalf("%s: %d: expect = %q, got = %q", url, i, a, b)
+			}
+			if a, b := "<p>", article.Html[page.HtmlOffset:][:3]; a != b {
+				t.Fatalf("%s: %d: expect = %q, got = %q", url, i, a, b)
+			}
+		}
+		var images []string
+		for _, img := range article.Images {
+			images = append(images, img.Url)
+		}
+		if a, b := []string{"http://example.com/a.png", "http://example.com/b.png"}, images; !reflect.DeepEqual(a, b) {
+			t.Fatalf("%s: expect = %v, got = %v", url, a, b)
+		}
+
+		opt.ArticleMaxPages = 2
+		if article, err = ParseArticle("token", url, opt); err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		if a, b := 2, len(article.Pages); a != b {
+			t.Fatalf("%s: expect = %v, got = %v", url, a, b)
+		}
+	}
+
+	opt := &Options{Client: testRedirectDoer(ts.URL), ArticleAllPages: true}
+	if _, err := ParseArticle("token", "http://example.com/broken/1", opt); err == nil {
+		t.Fatalf("expect error, got nil")
+	} else if apiErr, ok := err.(*Error); !ok || apiErr.ErrCode != 404 {
+		t.Fatalf("expect 404 error, got = %v", err)
+	}
+}
+
+func TestParseArticle_allPagesCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case "http://example.com/slow/1":
+			fmt.Fprint(w, `{"text": "Page one.", "nextPages": ["http://example.com/slow/2", "http://example.com/slow/3"]}`)
+		case "http://example.com/slow/2":
+			// Canceled by the failure of page 3.
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"Could not download page (404)","errorCode":404}`)
+		}
+	}))
+	defer ts.Close()
+
+	opt := &Options{Client: testRedirectDoer(ts.URL), ArticleAllPages: true}
+	_, err := ParseArticle("token", "http://example.com/slow/1", opt)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expect 404 error, got = %v", err)
+	}
+}
+
+var testJsonDataArticlePages = map[string]string{
+	"http://example.com/list/1": `{
+		"url": "http://example.com/list/1",
+		"text": "Page one.", "html": "<p>Page one.</p>", "numPages": "3",
+		"images": [{"url": "http://example.com/a.png", "primary": "true"}],
+		"nextPages": ["http://example.com/list/2", "http://example.com/list/3", "http://example.com/list/1"]
+	}`,
+	"http://example.com/list/2": `{
+		"text": "Page two.", "html": "<p>Page two.</p>",
+		"images": [{"url": "http://example.com/a.png"}, {"url": "http://example.com/b.png"}]
+	}`,
+	"http://example.com/list/3": `{"text": "Page three.", "html": "<p>Page three.</p>"}`,
+
+	"http://example.com/chain/1": `{
+		"text": "Page one.", "html": "<p>Page one.</p>",
+		"images": [{"url": "http://example.com/a.png", "primary": "true"}],
+		"nextPage": "http://example.com/chain/2"
+	}`,
+	"http://example.com/chain/2": `{
+		"text": "Page two.", "html": "<p>Page two.</p>",
+		"images": [{"url": "http://example.com/b.png"}],
+		"nextPage": "http://example.com/chain/3"
+	}`,
+	"http://example.com/chain/3": `{
+		"text": "Page three.", "html": "<p>Page three.</p>",
+		"nextPage": "http://example.com/chain/1"
+	}`,
+
+	"http://example.com/broken/1": `{
+		"text": "Page one.",
+		"nextPages": ["http://example.com/broken/2", "http://example.com/broken/3"]
+	}`,
+	"http://example.com/broken/3": `{"text": "Page three."}`,
+}
@@ -12,11 +12,26 @@ import (
 
 func TestArticle_type(t *testing.T) {
 	var a Article
-	a.Images = append(a.Images, articleImageType{})
-	a.Videos = append(a.Videos, articleVideoType{})
+	a.Images = append(a.Images, ArticleImage{})
+	a.Videos = append(a.Videos, ArticleVideo{})
 	_ = a
 }
 
+func TestArticle_primary(t *testing.T) {
+	var a Article
+	if a.PrimaryImage() != nil || a.PrimaryVideo() != nil {
+		t.Fatalf("expect nil")
+	}
+	a.Images = []ArticleImage{{Url: "a.png"}, {Url: "b.png", Primary: "true"}}
+	a.Videos = []ArticleVideo{{Url: "a.mp4"}, {Url: "b.mp4"}}
+	if a, b := "b.png", a.PrimaryImage().Url; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := "a.mp4", a.PrimaryVideo().Url; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
 func TestArticle_parseJson(t *testing.T) {
 	var result1 Article
 	if err := json.Unmarshal([]byte(testJsonDataArticle), &result1); err != nil {
@@ -74,12 +89,7 @@ var testArticleValue = Article{
 	Tags:          []string{"Data model", "Product (chemistry)", "Intelligence", "Technology"},
 	HumanLanguage: "en",
 	Images:        nil,
-	Videos: []struct {
-		Url         string `json:"url"`
-		PixelHeight int    `json:"pixelHeight"`
-		PixelWidth  int    `json:"pixelWidth"`
-		Primary     string `json:"primary"`
-	}{
+	Videos: []ArticleVideo{
 		{
 			Url:     "http://www.youtube.com/embed/lfcri5ungRo?feature=oembed",
 			Primary: "true",
@@ -5,6 +5,7 @@
 package diffbot
 
 import (
+	"context"
 	"encoding/json"
 )
 
@@ -42,6 +43,9 @@ type Classification struct {
 			Video       float64 `json:"video"`
 		} `json:"types"`
 	} `json:"stats"`
+	Warnings []DecodeWarning            `json:"-"` // Mismatched fields, see ParseJson.
+	Raw      json.RawMessage            `json:"-"` // The JSON data, see ParseJson.
+	Extra    map[string]json.RawMessage `json:"-"` // Unrecognised JSON fields, see ParseJson.
 }
 
 // type of Classification.Stats
@@ -229,17 +233,29 @@ type classificationStatsTypeType struct {
 //	+-------------+-----------------------------------------------------------------------------------+
 //
 func ParseClassification(token, url string, opt *Options) (*Classification, error) {
-	body, err := Diffbot("analyze", token, url, opt)
+	return ParseClassificationContext(context.Background(), token, url, opt)
+}
+
+// ParseClassificationContext like ParseClassification function, but carries a context.
+func ParseClassificationContext(ctx context.Context, token, url string, opt *Options) (*Classification, error) {
+	body, err := DiffbotContext(ctx, "analyze", token, url, opt)
 	if err != nil {
 		return nil, err
 	}
 	var result Classification
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := opt.parseJson(ctx, "analyze", body, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
+// ParseJson parses the JSON-encoded Classification data, see DecodeWarning. The
+// unknown fields are errors only in ParseClassification with Options.StrictFields.
+func (p *Classification) ParseJson(data []byte) (err error) {
+	p.Warnings, p.Raw, p.Extra, err = decodeResultJson(data, p)
+	return err
+}
+
 func (p *Classification) String() string {
 	d, _ := json.Marshal(p)
 	return string(d)
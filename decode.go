@@ -0,0 +1,441 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecodeWarning describes a JSON field whose value does not match
+// the Go field type.
+//
+// If Coerced is true, the value was converted (e.g. the number 3 to the
+// string "3", or the string "true" to the bool true) and decoded, else
+// the field was skipped.
+//
+// The ParseJson methods of the results (e.g. Article.ParseJson) report the
+// mismatched fields in Warnings, and keep the data in Raw and the top-level
+// fields which are not modelled in Extra. They return an error only if the
+// data is not a valid JSON object, the unknown fields are not checked. The
+// Parse functions (e.g. ParseArticle) return *UnknownFieldsError for the
+// unknown fields if Options.StrictFields is true.
+type DecodeWarning struct {
+	Field   string // Dotted JSON path, e.g. "images.0.pixelWidth"
+	Value   string // JSON value, truncated to 64 bytes
+	Type    string // Go type of the field
+	Coerced bool
+}
+
+func (p DecodeWarning) String() string {
+	if p.Coerced {
+		return fmt.Sprintf("diffbot: field %q: coerced %s to %s", p.Field, p.Value, p.Type)
+	}
+	return fmt.Sprintf("diffbot: field %q: skipped %s, can not decode into %s", p.Field, p.Value, p.Type)
+}
+
+// maxDecodeRetries limits the distinct mismatched fields of a document.
+const maxDecodeRetries = 64
+
+// decodeJson decodes data into v like json.Unmarshal, but tolerates
+// mismatched field types.
+//
+// Strings are accepted for numbers and booleans, and numbers or booleans are
+// accepted for strings. Other mismatched fields are skipped. Each mismatched
+// field is reported as a DecodeWarning. Syntax errors are returned as is.
+func decodeJson(data []byte, v interface{}) (warnings []DecodeWarning, err error) {
+	err = json.Unmarshal(data, v)
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return nil, err
+	}
+
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	for i := 0; i < maxDecodeRetries; i++ {
+		if typeErr.Field == "" {
+			return warnings, err
+		}
+		warning := DecodeWarning{
+			Field: typeErr.Field,
+			Type:  typeErr.Type.String(),
+		}
+		var fixed bool
+		walkJsonPath(tree, strings.Split(typeErr.Field, "."), func(value interface{}) (interface{}, bool) {
+			if !jsonKindMatch(value, typeErr) {
+				return value, true
+			}
+			fixed = true
+			return coerceJsonValue(value, typeErr, &warning)
+		})
+		if !fixed {
+			return warnings, err
+		}
+		warnings = append(warnings, warning)
+
+		if data, err = json.Marshal(tree); err != nil {
+			return warnings, err
+		}
+		rv.Set(reflect.Zero(rv.Type()))
+		if err = json.Unmarshal(data, v); err == nil {
+			return warnings, nil
+		}
+		if !errors.As(err, &typeErr) {
+			return warnings, err
+		}
+	}
+	return warnings, err
+}
+
+// walkJsonPath replaces each value on the path with fn(value).
+// If fn returns false, the value is removed (or set to null in arrays).
+//
+// The arrays on the path are walked through, unless the path holds the
+// array index (e.g. "images.0.pixelWidth").
+func walkJsonPath(node interface{}, path []string, fn func(value interface{}) (interface{}, bool)) {
+	switch node := node.(type) {
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil {
+			for _, elem := range node {
+				walkJsonPath(elem, path, fn)
+			}
+			return
+		}
+		if i < 0 || i >= len(node) {
+			return
+		}
+		if len(path) > 1 {
+			walkJsonPath(node[i], path[1:], fn)
+			return
+		}
+		if v, ok := fn(node[i]); ok {
+			node[i] = v
+		} else {
+			node[i] = nil
+		}
+	case map[string]interface{}:
+		key, ok := lookupJsonKey(node, path[0])
+		if !ok {
+			return
+		}
+		if len(path) > 1 {
+			walkJsonPath(node[key], path[1:], fn)
+			return
+		}
+		if v, ok := fn(node[key]); ok {
+			node[key] = v
+		} else {
+			delete(node, key)
+		}
+	}
+}
+
+// lookupJsonKey finds the key like encoding/json, which prefers
+// an exact match but accepts a case-insensitive match.
+func lookupJsonKey(node map[string]interface{}, name string) (string, bool) {
+	if _, ok := node[name]; ok {
+		return name, true
+	}
+	for key := range node {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// coerceJsonValue converts the mismatched value to the kind of typeErr.Type.
+// The elements of an array value are converted one by one.
+func coerceJsonValue(value interface{}, typeErr *json.UnmarshalTypeError, warning *DecodeWarning) (interface{}, bool) {
+	if warning.Value == "" {
+		warning.Value = jsonValueString(value)
+	}
+	if typeErr.Value == "array" || typeErr.Value == "object" {
+		return nil, false
+	}
+
+	if values, ok := value.([]interface{}); ok {
+		kind := typeErr.Type.Kind()
+		if kind == reflect.Slice || kind == reflect.Array {
+			return nil, false
+		}
+		for i, elem := range values {
+			if !jsonKindMatch(elem, typeErr) {
+				continue
+			}
+			warning.Value = jsonValueString(elem)
+			fix, ok := coerceJsonScalar(elem, kind)
+			if !ok {
+				return nil, false
+			}
+			values[i] = fix
+		}
+		warning.Coerced = true
+		return values, true
+	}
+
+	fix, ok := coerceJsonScalar(value, typeErr.Type.Kind())
+	warning.Coerced = ok
+	return fix, ok
+}
+
+func coerceJsonScalar(value interface{}, kind reflect.Kind) (interface{}, bool) {
+	switch kind {
+	case reflect.String:
+		switch v := value.(type) {
+		case json.Number:
+			return v.String(), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch v := value.(type) {
+		case string:
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return json.Number("0"), true
+			}
+			if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return json.Number(v), true
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return json.Number(strconv.FormatInt(int64(f), 10)), true
+			}
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return json.Number(strconv.FormatInt(int64(f), 10)), true
+			}
+		case bool:
+			if v {
+				return json.Number("1"), true
+			}
+			return json.Number("0"), true
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case string:
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return json.Number("0"), true
+			}
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return json.Number(v), true
+			}
+		}
+
+	case reflect.Bool:
+		switch v := value.(type) {
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, true
+			}
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f != 0, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// jsonKindMatch reports whether the value is the mismatched one of typeErr.
+// An array value matches if any of its elements matches.
+func jsonKindMatch(value interface{}, typeErr *json.UnmarshalTypeError) bool {
+	var kind string
+	switch v := value.(type) {
+	case string:
+		kind = "string"
+	case json.Number:
+		kind = "number"
+	case bool:
+		kind = "bool"
+	case map[string]interface{}:
+		kind = "object"
+	case []interface{}:
+		if typeErr.Value == "array" {
+			return true
+		}
+		for _, elem := range v {
+			if jsonKindMatch(elem, typeErr) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+	// e.g. "number" or "number 3.5"
+	return typeErr.Value == kind || strings.HasPrefix(typeErr.Value, kind+" ")
+}
+
+func jsonValueString(value interface{}) string {
+	d, _ := json.Marshal(value)
+	if len(d) > 64 {
+		return string(d[:61]) + "..."
+	}
+	return string(d)
+}
+
+type jsonParser interface {
+	ParseJson(data []byte) error
+}
+
+// parseJson parses the response body of the method into v.
+//
+// If p.StrictFields is true, the unknown JSON fields are reported
+// as *UnknownFieldsError. The errors are wrapped in *DecodeError.
+func (p *Options) parseJson(ctx context.Context, method string, body []byte, v jsonParser) error {
+	err := v.ParseJson(body)
+	if err == nil && p != nil && p.StrictFields {
+		var fields []string
+		if fields, err = UnknownFields(body, v); err == nil && len(fields) != 0 {
+			err = &UnknownFieldsError{Fields: fields}
+		}
+	}
+	if err != nil {
+		err = &DecodeError{Method: method, Err: err}
+		p.logDecodeError(ctx, method, err)
+	}
+	return err
+}
+
+// UnknownFieldsError reports the JSON fields which are not modelled
+// by the result type, see Options.StrictFields.
+type UnknownFieldsError struct {
+	Fields []string // Dotted JSON paths, e.g. "images.0.alt"
+}
+
+func (p *UnknownFieldsError) Error() string {
+	return "diffbot: unknown fields: " + strings.Join(p.Fields, ", ")
+}
+
+// UnknownFields returns the dotted paths of the JSON fields in data
+// which have no matching field in v, e.g. "images.0.alt".
+//
+// The map and interface{} fields accept any JSON fields.
+func UnknownFields(data []byte, v interface{}) ([]string, error) {
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	var fields []string
+	walkUnknownFields(tree, reflect.TypeOf(v), "", &fields)
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func walkUnknownFields(node interface{}, t reflect.Type, path string, fields *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch node := node.(type) {
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, elem := range node {
+			walkUnknownFields(elem, t.Elem(), joinJsonPath(path, strconv.Itoa(i)), fields)
+		}
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		known := jsonFields(t)
+		for key, value := range node {
+			ft, ok := known[key]
+			if !ok {
+				for name, typ := range known {
+					if strings.EqualFold(name, key) {
+						ft, ok = typ, true
+						break
+					}
+				}
+			}
+			if !ok {
+				*fields = append(*fields, joinJsonPath(path, key))
+				continue
+			}
+			walkUnknownFields(value, ft, joinJsonPath(path, key), fields)
+		}
+	}
+}
+
+// jsonFields returns the JSON names and types of the struct fields.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// decodeResultJson decodes data into the result v (e.g. an *Article) for
+// its ParseJson method, see DecodeWarning. It returns the fields Warnings,
+// Raw and Extra of the result.
+func decodeResultJson(data []byte, v interface{}) (warnings []DecodeWarning, raw json.RawMessage, extra map[string]json.RawMessage, err error) {
+	if warnings, err = decodeJson(data, v); err != nil {
+		return nil, nil, nil, err
+	}
+	return warnings, append(json.RawMessage(nil), data...), extraJsonFields(data, v), nil
+}
+
+// extraJsonFields returns the top-level fields of the JSON object data
+// which have no matching field in v.
+func extraJsonFields(data []byte, v interface{}) map[string]json.RawMessage {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for name := range jsonFields(t) {
+		for key := range object {
+			if key == name || strings.EqualFold(key, name) {
+				delete(object, key)
+			}
+		}
+	}
+	if len(object) == 0 {
+		return nil
+	}
+	return object
+}
+
+func joinJsonPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
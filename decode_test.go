@@ -0,0 +1,201 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestArticle_parseJsonTolerant(t *testing.T) {
+	var result Article
+	if err := result.ParseJson([]byte(testJsonDataArticleMismatched)); err != nil {
+		t.Fatal(err)
+	}
+
+	if a, b := "Diffbot's New Product API", result.Title; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := "3", result.NumPages; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := []string{"e-commerce", "2013"}, result.Tags; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 2, len(result.Images); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 300, result.Images[0].PixelWidth; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 150, result.Images[1].PixelWidth; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := "true", result.Images[0].Primary; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := "", result.Author; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+
+	expect := map[string]DecodeWarning{
+		"numPages":          {Field: "numPages", Value: "3", Type: "string", Coerced: true},
+		"author":            {Field: "author", Value: `{"name":"John Davi"}`, Type: "string", Coerced: false},
+		"tags":              {Field: "tags", Value: "2013", Type: "string", Coerced: true},
+		"images.pixelWidth": {Field: "images.pixelWidth", Value: `"300"`, Type: "int", Coerced: true},
+		"images.primary":    {Field: "images.primary", Value: "true", Type: "string", Coerced: true},
+	}
+	if a, b := len(expect), len(result.Warnings); a != b {
+		t.Fatalf("expect = %v, got = %v", a, result.Warnings)
+	}
+	for _, w := range result.Warnings {
+		// Newer Go versions put the array index in the path, e.g. "images.0.pixelWidth".
+		w.Field = regexp.MustCompile(`\.\d+`).ReplaceAllString(w.Field, "")
+		if a, b := expect[w.Field], w; a != b {
+			t.Fatalf("expect = %v, got = %v", a, b)
+		}
+	}
+}
+
+func TestImage_parseJsonTolerant(t *testing.T) {
+	var result Image
+	if err := result.ParseJson([]byte(`{"images":[{"displayWidth":"300","size":"1024","pixelHeight":1.5e2}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := 300, result.Images[0].DisplayWidth; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 1024, result.Images[0].Size; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 150, result.Images[0].PixelHeight; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 3, len(result.Warnings); a != b {
+		t.Fatalf("expect = %v, got = %v", a, result.Warnings)
+	}
+}
+
+func TestDecodeJson(t *testing.T) {
+	var v struct {
+		Ok    bool    `json:"ok"`
+		Score float64 `json:"score"`
+	}
+	warnings, err := decodeJson([]byte(`{"ok":"true","score":"0.5"}`), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Ok || v.Score != 0.5 || len(warnings) != 2 {
+		t.Fatalf("got = %+v, %v", v, warnings)
+	}
+
+	warnings, err = decodeJson([]byte(`{"ok":true,"score":1}`), &v)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("got = %v, %v", err, warnings)
+	}
+
+	if _, err = decodeJson([]byte(`{"ok":`), &v); err == nil {
+		t.Fatalf("expect error, got nil")
+	}
+	if _, err = decodeJson([]byte(`[1,2]`), &v); err == nil {
+		t.Fatalf("expect error, got nil")
+	}
+}
+
+const testJsonDataArticleMismatched = `
+{
+  "type": "article",
+  "title": "Diffbot's New Product API",
+  "author": {"name": "John Davi"},
+  "numPages": 3,
+  "tags": ["e-commerce", 2013],
+  "images": [
+    {
+      "url": "http://www.diffbot.com/img/a.png",
+      "pixelWidth": "300",
+      "primary": true
+    },
+    {
+      "url": "http://www.diffbot.com/img/b.png",
+      "pixelWidth": 150,
+      "primary": "false"
+    }
+  ]
+}
+`
+
+func TestArticle_extra(t *testing.T) {
+	var result Article
+	if err := result.ParseJson([]byte(testJsonDataArticle)); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := testJsonDataArticle, string(result.Raw); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	var keys []string
+	for key := range result.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if a, b := []string{"stats", "summary", "supertags"}, keys; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := `{"confidence":"0.800"}`, string(result.Extra["stats"]); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	fields, err := UnknownFields([]byte(`{
+		"type": "article",
+		"Title": "case-insensitive",
+		"meta": {"any": {"thing": 1}},
+		"images": [{"url": "a.png", "alt": "A"}, {"url": "b.png"}],
+		"videos": [{"url": "a.mp4", "duration": 60}],
+		"stats": {"confidence": "0.800"}
+	}`), &Article{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := []string{"images.0.alt", "stats", "videos.0.duration"}, fields; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestOptions_strictFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"article","title":"Diffbot","sentiment":0.5}`)
+	}))
+	defer ts.Close()
+
+	opt := &Options{Client: testRedirectDoer(ts.URL)}
+	article, err := ParseArticle("token", "http://example.com/", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := "0.5", string(article.Extra["sentiment"]); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+
+	opt.StrictFields = true
+	_, err = ParseArticle("token", "http://example.com/", opt)
+	var fieldsErr *UnknownFieldsError
+	if !errors.As(err, &fieldsErr) {
+		t.Fatalf("expect UnknownFieldsError, got = %v", err)
+	}
+	if a, b := []string{"sentiment"}, fieldsErr.Fields; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expect DecodeError, got = %v", err)
+	}
+}
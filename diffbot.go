@@ -5,10 +5,14 @@
 package diffbot
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	urlPkg "net/url"
+	"time"
 )
 
 const (
@@ -19,52 +23,153 @@ const (
 // and machine learning to automatically recognize
 // and structure specific page-types.
 func Diffbot(method, token, url string, opt *Options) (body []byte, err error) {
-	return DiffbotServer(DefaultServer, method, token, url, opt)
+	return DiffbotServerContext(context.Background(), DefaultServer, method, token, url, opt)
+}
+
+// DiffbotContext like Diffbot function, but carries a context.
+func DiffbotContext(ctx context.Context, method, token, url string, opt *Options) (body []byte, err error) {
+	return DiffbotServerContext(ctx, DefaultServer, method, token, url, opt)
 }
 
 // DiffbotServer like Diffbot function, but support custom server.
 func DiffbotServer(server, method, token, url string, opt *Options) (body []byte, err error) {
-	req, err := http.NewRequest("GET", makeRequestUrl(server, method, token, url, opt), nil)
+	return DiffbotServerContext(context.Background(), server, method, token, url, opt)
+}
+
+// DiffbotServerContext like DiffbotServer function, but carries a context.
+//
+// The request is sent by opt.Client wrapped with opt.Middlewares,
+// and the opt.Hooks are called around it. If opt.Metrics is not nil,
+// the call is recorded to it. If opt.Tracer is not nil, the call is
+// traced with a span named "diffbot.<method>". If opt.Logger is not nil,
+// the call is logged to it with the token redacted.
+func DiffbotServerContext(ctx context.Context, server, method, token, url string, opt *Options) (body []byte, err error) {
+	// Only the span of this call is annotated, not a span of the caller.
+	var span Span = noopSpan{}
+	if tracer := opt.tracer(); tracer != nil {
+		ctx, span = tracer.Start(ctx, "diffbot."+method)
+		ctx = ContextWithSpan(ctx, span)
+		defer span.End()
+		span.SetAttributes(
+			Attribute{Key: AttrMethod, Value: method},
+			Attribute{Key: AttrTargetHost, Value: urlHost(url)},
+		)
+	}
+
+	ctx = contextWithCallLog(ctx, opt, method)
+	req, err := http.NewRequestWithContext(ctx, "GET", makeRequestUrl(server, method, token, url, opt), nil)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 	if opt != nil && opt.CustomHeader != nil {
-		req.Header = opt.CustomHeader
+		req.Header = opt.CustomHeader.Clone()
+	}
+
+	hooks := opt.hooks()
+	hooks.beforeRequest(req)
+	opt.logRequest(ctx, method, req)
+	start := time.Now()
+	resp, body, err := doRequest(method, opt.doer(), req, hooks)
+	duration := time.Since(start)
+	opt.metrics().Observe(method, duration, len(body), err)
+	opt.logResponse(ctx, method, req, resp, body, duration, err)
+
+	if resp != nil {
+		span.SetAttributes(Attribute{Key: AttrStatusCode, Value: resp.StatusCode})
 	}
-	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		if apiErr, ok := err.(*Error); ok {
+			span.SetAttributes(Attribute{Key: AttrErrCode, Value: apiErr.ErrCode})
+		}
+		span.RecordError(err)
+		hooks.onError(req, err)
+	}
+	return
+}
+
+func doRequest(method string, doer Doer, req *http.Request, hooks *Hooks) (resp *http.Response, body []byte, err error) {
+	if resp, err = doer.Do(req); err != nil {
+		err = &TransportError{Method: method, Err: err}
 		return
 	}
 
 	defer resp.Body.Close()
 	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		err = &TransportError{Method: method, Err: err}
+		return
+	}
+	hooks.afterResponse(req, resp, body)
 
+	var apiError *Error
 	if resp.StatusCode != http.StatusOK {
-		if len(body) != 0 {
-			var apiError Error
-			if err = apiError.ParseJson(string(body)); err != nil {
-				err = &Error{
-					ErrCode:    resp.StatusCode,
-					ErrMessage: string(body),
-				}
-				return
-			} else {
-				err = &apiError
-				return
-			}
-		} else {
-			err = &Error{
+		apiError = &Error{}
+		switch {
+		case len(body) == 0:
+			*apiError = Error{
 				ErrCode:    resp.StatusCode,
 				ErrMessage: resp.Status,
 			}
-			return
+		case apiError.ParseJson(string(body)) != nil:
+			*apiError = Error{
+				ErrCode:    resp.StatusCode,
+				ErrMessage: string(body),
+			}
+		case apiError.ErrCode == 0:
+			apiError.ErrCode = resp.StatusCode
 		}
+	} else if apiError = parseErrorEnvelope(body); apiError == nil {
+		return
 	}
+	apiError.HttpStatus = resp.StatusCode
+	apiError.Header = resp.Header
+	apiError.RequestUrl = redactToken(req.URL.String())
+	err = apiError
 	return
 }
 
+// parseErrorEnvelope parses the error which is returned with HTTP 200,
+// e.g. {"error":"Could not download page (404)","errorCode":404}.
+// It returns nil if body is not an error.
+//
+// If the errorCode field is missing, the ErrCode is 500.
+func parseErrorEnvelope(body []byte) *Error {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return nil
+	}
+	var envelope struct {
+		ErrCode    *int    `json:"errorCode"`
+		ErrMessage *string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+	hasCode := envelope.ErrCode != nil && *envelope.ErrCode != 0
+	hasMessage := envelope.ErrMessage != nil && *envelope.ErrMessage != ""
+	if !hasCode && !hasMessage {
+		return nil
+	}
+	var apiError Error
+	if err := apiError.ParseJson(string(body)); err != nil {
+		return nil
+	}
+	if apiError.ErrCode == 0 {
+		apiError.ErrCode = http.StatusInternalServerError
+	}
+	return &apiError
+}
+
 func makeRequestUrl(server, method, token, webUrl string, opt *Options) string {
 	return fmt.Sprintf("%s/%s?token=%s&url=%s%s",
 		server, method, token, urlPkg.QueryEscape(webUrl), opt.MethodParamString(method),
 	)
 }
+
+func urlHost(webUrl string) string {
+	if u, err := urlPkg.Parse(webUrl); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
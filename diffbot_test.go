@@ -3,3 +3,181 @@
 // license that can be found in the LICENSE file.
 
 package diffbot
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	urlPkg "net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffbotServer_middlewares(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Not authorized API token.","errorCode":401}`)
+			return
+		}
+		fmt.Fprint(w, `{"type":"article"}`)
+	}))
+	defer ts.Close()
+
+	var trace []string
+	tracer := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				trace = append(trace, name+".before")
+				resp, err := next.Do(req)
+				trace = append(trace, name+".after")
+				return resp, err
+			})
+		}
+	}
+	auth := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer abc")
+			return next.Do(req)
+		})
+	}
+	opt := &Options{
+		CustomHeader: http.Header{"X-Forward-Cookie": {"abc=123"}},
+		Middlewares:  []Middleware{tracer("a"), tracer("b"), auth},
+		Hooks: &Hooks{
+			BeforeRequest: func(req *http.Request) {
+				trace = append(trace, "hooks.before")
+			},
+			AfterResponse: func(req *http.Request, resp *http.Response, body []byte) {
+				trace = append(trace, "hooks.after:"+string(body))
+			},
+			OnError: func(req *http.Request, err error) {
+				trace = append(trace, "hooks.error")
+			},
+		},
+	}
+
+	body, err := DiffbotServer(ts.URL, "article", "token", "http://example.com/", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := `{"type":"article"}`, string(body); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	expect := []string{
+		"hooks.before",
+		"a.before", "b.before", "b.after", "a.after",
+		`hooks.after:{"type":"article"}`,
+	}
+	if !reflect.DeepEqual(expect, trace) {
+		t.Fatalf("expect = %v, got = %v", expect, trace)
+	}
+	if _, ok := opt.CustomHeader["Authorization"]; ok {
+		t.Fatalf("middleware modified the Options.CustomHeader")
+	}
+
+	trace = nil
+	opt.Middlewares = nil
+	if _, err = DiffbotServer(ts.URL, "article", "token", "http://example.com/", opt); err == nil {
+		t.Fatalf("expect error, got nil")
+	}
+	if apiErr, ok := err.(*Error); !ok || apiErr.ErrCode != 401 {
+		t.Fatalf("expect 401 api error, got = %v", err)
+	}
+	if a, b := "hooks.error", trace[len(trace)-1]; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestChain(t *testing.T) {
+	var doer Doer = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+	if Chain(doer) == nil {
+		t.Fatalf("expect doer, got nil")
+	}
+	if Chain(doer, nil, nil) == nil {
+		t.Fatalf("expect doer, got nil")
+	}
+}
+
+// testRedirectDoer sends the requests to the test server instead of the DefaultServer.
+func testRedirectDoer(server string) Doer {
+	u, err := urlPkg.Parse(server)
+	if err != nil {
+		panic(err)
+	}
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, "/v2")
+		return http.DefaultClient.Do(req)
+	})
+}
+
+func TestDiffbot_errorWithStatusOK(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		fmt.Fprint(w, testErrorEnvelopes[method])
+	}))
+	defer ts.Close()
+
+	opt := &Options{Client: testRedirectDoer(ts.URL)}
+	url := "http://example.com/missing"
+	for method, parse := range map[string]func() error{
+		"article":   func() error { _, err := ParseArticle("token", url, opt); return err },
+		"image":     func() error { _, err := ParseImage("token", url, opt); return err },
+		"product":   func() error { _, err := ParseProduct("token", url, opt); return err },
+		"analyze":   func() error { _, err := ParseClassification("token", url, opt); return err },
+		"frontpage": func() error { _, err := ParseFrontpage("token", url, opt); return err },
+	} {
+		err := parse()
+		apiErr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("%s: expect *Error, got = %#v", method, err)
+		}
+		if a, b := 200, apiErr.HttpStatus; a != b {
+			t.Fatalf("%s: expect = %v, got = %v", method, a, b)
+		}
+		if a, b := testErrorEnvelopeCodes[method], apiErr.ErrCode; a != b {
+			t.Fatalf("%s: expect = %v, got = %v", method, a, b)
+		}
+	}
+
+	body, err := DiffbotServer(ts.URL, "article", "token", url, nil)
+	if err == nil {
+		t.Fatalf("expect error, got = %s", body)
+	}
+}
+
+func TestParseErrorEnvelope(t *testing.T) {
+	for i, s := range []string{
+		``,
+		`<dml></dml>`,
+		`{"type":"article","title":"error"}`,
+		`{"type":"article","error":""}`,
+		`{"type":"analyze","stats":{"types":{"error":0.1}}}`,
+		testJsonDataClassification,
+	} {
+		if err := parseErrorEnvelope([]byte(s)); err != nil {
+			t.Fatalf("%d: expect nil, got = %v", i, err)
+		}
+	}
+}
+
+var testErrorEnvelopes = map[string]string{
+	"article":   `{"error":"Could not download page (404)","errorCode":404}`,
+	"image":     `{"errorCode":401,"error":"Not authorized API token."}`,
+	"product":   ` {"error":"Could not parse the page."}`,
+	"analyze":   `{"error":"Request timed out","errorCode":500}`,
+	"frontpage": `{"errorCode":429,"error":"Too many requests."}`,
+}
+
+var testErrorEnvelopeCodes = map[string]int{
+	"article":   404,
+	"image":     401,
+	"product":   500,
+	"analyze":   500,
+	"frontpage": 429,
+}
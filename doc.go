@@ -121,6 +121,23 @@ You can call Diffbot with custom headers:
 		...
 	}
 
+Middlewares and Hooks
+
+You can wrap the HTTP client with middlewares, and observe the calls with hooks:
+
+	func main() {
+		opt := &diffbot.Options{
+			Middlewares: []diffbot.Middleware{authMiddleware, chaosMiddleware},
+			Hooks: &diffbot.Hooks{
+				OnError: func(req *http.Request, err error) {
+					log.Println(req.URL.Path, err)
+				},
+			},
+		}
+		respBody, err := diffbot.Diffbot("article", token, url, opt)
+		...
+	}
+
 Error handling
 
 If diffbot server return error message, it will be converted to the `diffbot.Error`:
@@ -136,6 +153,34 @@ If diffbot server return error message, it will be converted to the `diffbot.Err
 		fmt.Println(string(respBody))
 	}
 
+The transport failures are wrapped in `diffbot.TransportError`, and the decode
+failures are wrapped in `diffbot.DecodeError`. Use errors.Is with the sentinel
+errors to test the kind of failure:
+
+	func main() {
+		article, err := diffbot.ParseArticle(token, url, nil)
+		switch {
+		case errors.Is(err, diffbot.ErrUnauthorized):
+			log.Fatal("invalid token")
+		case diffbot.IsRetryable(err):
+			// try again later
+		}
+		...
+	}
+
+Feeds
+
+The extracted articles or a frontpage can be encoded as RSS 2.0, Atom 1.0
+or JSON Feed 1.1:
+
+	func main() {
+		page, err := diffbot.ParseFrontpage(token, url, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		diffbot.NewFrontpageFeed(page).WriteAtom(os.Stdout)
+	}
+
 Other
 
 Diffbot API Document at http://diffbot.com/dev/docs/ or http://diffbot.com/products/.
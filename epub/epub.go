@@ -0,0 +1,599 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package epub writes the extracted Diffbot articles as an EPUB 3 book.
+//
+// Each article becomes a chapter, and the images of the articles are
+// downloaded and embedded in the book:
+//
+//	func main() {
+//		article, err := diffbot.ParseArticle(token, url, nil)
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		f, err := os.Create("article.epub")
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		defer f.Close()
+//		if err := epub.Write(f, []*diffbot.Article{article}, nil); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// See https://www.w3.org/TR/epub-33/.
+package epub
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diffbot/diffbot-go-client"
+)
+
+// DefaultMaxImageBytes is the default max size of an embedded image.
+const DefaultMaxImageBytes = 10 << 20
+
+// Options specifies the metadata of the book, and how to download the images.
+type Options struct {
+	Title      string    // The title of the first article if empty
+	Author     []string  // The authors of the articles if empty
+	Language   string    // The HumanLanguage of the first article, or "en" if empty
+	Identifier string    // A "urn:uuid:" made from the article URLs if empty
+	Date       time.Time // The latest date of the articles if zero
+	Modified   time.Time // The current time if zero
+
+	// CoverUrl is the URL of the cover image. The primary image of
+	// the first article with images is used if empty.
+	CoverUrl string
+
+	// Client downloads the images, http.DefaultClient if nil.
+	Client diffbot.Doer
+
+	// NoImages removes the images instead of downloading them.
+	NoImages bool
+
+	// MaxImageBytes limits the size of an image, DefaultMaxImageBytes if zero.
+	// The larger images are removed.
+	MaxImageBytes int64
+}
+
+func (p *Options) client() diffbot.Doer {
+	if p.Client == nil {
+		return http.DefaultClient
+	}
+	return p.Client
+}
+
+func (p *Options) maxImageBytes() int64 {
+	if p.MaxImageBytes <= 0 {
+		return DefaultMaxImageBytes
+	}
+	return p.MaxImageBytes
+}
+
+// Write writes the articles as an EPUB 3 book.
+func Write(w io.Writer, articles []*diffbot.Article, opt *Options) error {
+	return WriteContext(context.Background(), w, articles, opt)
+}
+
+// WriteContext like Write function, but carries a context for the image downloads.
+//
+// The images which can not be downloaded, or which are not JPEG, PNG, GIF
+// or WebP images, are removed from the book.
+func WriteContext(ctx context.Context, w io.Writer, articles []*diffbot.Article, opt *Options) error {
+	if len(articles) == 0 {
+		return fmt.Errorf("epub: no articles")
+	}
+	if opt == nil {
+		opt = &Options{}
+	}
+	book := newBook(articles, opt)
+	if err := book.fetchImages(ctx); err != nil {
+		return err
+	}
+	return book.write(w)
+}
+
+// bookImage is an embedded image.
+type bookImage struct {
+	id        string
+	href      string // Relative to the OEBPS directory, e.g. "images/1.jpg"
+	mediaType string
+	data      []byte
+}
+
+type bookFile struct {
+	name string
+	data []byte
+}
+
+type bookChapter struct {
+	id      string
+	href    string
+	article *diffbot.Article
+	images  []string // The image URLs in order, including Article.Images
+}
+
+type book struct {
+	opt        *Options
+	title      string
+	authors    []string
+	language   string
+	identifier string
+	date       time.Time
+	modified   time.Time
+	coverUrl   string
+
+	chapters []*bookChapter
+	images   map[string]*bookImage // by URL
+	order    []string              // The image URLs in order
+}
+
+func newBook(articles []*diffbot.Article, opt *Options) *book {
+	p := &book{
+		opt:        opt,
+		title:      opt.Title,
+		authors:    opt.Author,
+		language:   opt.Language,
+		identifier: opt.Identifier,
+		date:       opt.Date,
+		modified:   opt.Modified,
+		coverUrl:   opt.CoverUrl,
+		images:     make(map[string]*bookImage),
+	}
+	hash := sha1.New()
+	seenAuthors := make(map[string]bool)
+	for i, article := range articles {
+		chapter := &bookChapter{
+			id:      fmt.Sprintf("chapter-%03d", i+1),
+			href:    fmt.Sprintf("chapter-%03d.xhtml", i+1),
+			article: article,
+		}
+		chapter.images = articleImageUrls(article)
+		p.chapters = append(p.chapters, chapter)
+
+		io.WriteString(hash, article.Url+"\n")
+		if p.title == "" {
+			p.title = article.Title
+		}
+		if p.language == "" {
+			p.language = article.HumanLanguage
+		}
+		if len(opt.Author) == 0 && article.Author != "" && !seenAuthors[article.Author] {
+			seenAuthors[article.Author] = true
+			p.authors = append(p.authors, article.Author)
+		}
+		if opt.Date.IsZero() {
+			if t, err := article.DateTime(); err == nil && t.After(p.date) {
+				p.date = t
+			}
+		}
+		if p.coverUrl == "" && !opt.NoImages {
+			if img := article.PrimaryImage(); img != nil {
+				p.coverUrl = img.Url
+			}
+		}
+	}
+	if p.title == "" {
+		p.title = "Articles"
+	}
+	if p.language == "" {
+		p.language = "en"
+	}
+	if p.identifier == "" {
+		p.identifier = "urn:uuid:" + uuidFromHash(hash.Sum(nil))
+	}
+	if p.modified.IsZero() {
+		p.modified = time.Now()
+	}
+	return p
+}
+
+// uuidFromHash formats the SHA-1 hash as a name-based (version 5) UUID.
+func uuidFromHash(sum []byte) string {
+	u := sum[:16]
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// sanitizePolicy returns the policy of the chapter content. The images
+// are rewritten by rewrite, and the data URLs are allowed.
+func sanitizePolicy(rewrite func(tag, attr, url string) string) *diffbot.SanitizePolicy {
+	policy := diffbot.DefaultSanitizePolicy()
+	policy.URLSchemes = append(policy.URLSchemes, "data")
+	policy.RewriteURL = rewrite
+	return policy
+}
+
+// articleImageUrls returns the image URLs of the html, and then the
+// Article.Images which are not in the html.
+func articleImageUrls(article *diffbot.Article) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	article.SanitizedHTML(sanitizePolicy(func(tag, attr, url string) string {
+		if tag == "img" && attr == "src" && !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+		return url
+	}))
+	for _, img := range article.Images {
+		if img.Url != "" && !seen[img.Url] {
+			seen[img.Url] = true
+			urls = append(urls, img.Url)
+		}
+	}
+	return urls
+}
+
+func (p *book) fetchImages(ctx context.Context) error {
+	if p.opt.NoImages {
+		return nil
+	}
+	var urls []string
+	if p.coverUrl != "" {
+		urls = append(urls, p.coverUrl)
+	}
+	for _, chapter := range p.chapters {
+		urls = append(urls, chapter.images...)
+	}
+	for _, url := range urls {
+		if _, ok := p.images[url]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, mediaType, err := p.fetchImage(ctx, url)
+		if err != nil {
+			p.images[url] = nil // removed
+			continue
+		}
+		n := len(p.order) + 1
+		p.images[url] = &bookImage{
+			id:        fmt.Sprintf("image-%03d", n),
+			href:      fmt.Sprintf("images/%03d%s", n, imageExts[mediaType]),
+			mediaType: mediaType,
+			data:      data,
+		}
+		p.order = append(p.order, url)
+	}
+	return nil
+}
+
+// imageExts maps the supported image types to the file extensions.
+var imageExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+func (p *book) fetchImage(ctx context.Context, url string) (data []byte, mediaType string, err error) {
+	if strings.HasPrefix(url, "data:") {
+		data, err = decodeDataUrl(url)
+	} else {
+		data, err = p.download(ctx, url)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > p.opt.maxImageBytes() {
+		return nil, "", fmt.Errorf("epub: image %s is too large", url)
+	}
+	mediaType = http.DetectContentType(data)
+	if _, ok := imageExts[mediaType]; !ok {
+		return nil, "", fmt.Errorf("epub: unsupported image type %s: %s", mediaType, url)
+	}
+	return data, mediaType, nil
+}
+
+func (p *book) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.opt.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("epub: %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, p.opt.maxImageBytes()+1))
+}
+
+// decodeDataUrl decodes a base64 data URL, e.g. "data:image/png;base64,...".
+func decodeDataUrl(url string) ([]byte, error) {
+	i := strings.Index(url, ",")
+	if i < 0 || !strings.HasSuffix(url[:i], ";base64") {
+		return nil, fmt.Errorf("epub: unsupported data url")
+	}
+	return base64.StdEncoding.DecodeString(url[i+1:])
+}
+
+// imageHref returns the href of the embedded image, or "" if the image
+// is removed.
+func (p *book) imageHref(url string) string {
+	if img := p.images[url]; img != nil {
+		return img.href
+	}
+	return ""
+}
+
+func (p *book) write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	// The mimetype must be the first entry, stored without compression
+	// and extra fields.
+	mimetype := []byte("application/epub+zip")
+	fw, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(mimetype); err != nil {
+		return err
+	}
+
+	files := []bookFile{
+		{"META-INF/container.xml", []byte(containerXml)},
+		{"OEBPS/content.opf", p.packageDocument()},
+		{"OEBPS/nav.xhtml", p.navDocument()},
+		{"OEBPS/toc.ncx", p.ncxDocument()},
+		{"OEBPS/style.css", []byte(styleCss)},
+	}
+	if p.coverImage() != nil {
+		files = append(files, bookFile{"OEBPS/cover.xhtml", p.coverDocument()})
+	}
+	for _, chapter := range p.chapters {
+		files = append(files, bookFile{"OEBPS/" + chapter.href, p.chapterDocument(chapter)})
+	}
+	for _, url := range p.order {
+		img := p.images[url]
+		files = append(files, bookFile{"OEBPS/" + img.href, img.data})
+	}
+
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: p.modified,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (p *book) coverImage() *bookImage {
+	if p.coverUrl == "" {
+		return nil
+	}
+	return p.images[p.coverUrl]
+}
+
+const containerXml = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const styleCss = `body { font-family: serif; line-height: 1.4; }
+h1 { font-size: 1.6em; }
+.byline, .source { color: #666; font-size: 0.9em; }
+figure { margin: 1em 0; text-align: center; }
+img { max-width: 100%; }
+`
+
+func (p *book) packageDocument() []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + esc(p.language) + `">` + "\n")
+	b.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	b.WriteString(`    <dc:identifier id="book-id">` + esc(p.identifier) + `</dc:identifier>` + "\n")
+	b.WriteString(`    <dc:title>` + esc(p.title) + `</dc:title>` + "\n")
+	b.WriteString(`    <dc:language>` + esc(p.language) + `</dc:language>` + "\n")
+	for _, author := range p.authors {
+		b.WriteString(`    <dc:creator>` + esc(author) + `</dc:creator>` + "\n")
+	}
+	if !p.date.IsZero() {
+		b.WriteString(`    <dc:date>` + p.date.UTC().Format(time.RFC3339) + `</dc:date>` + "\n")
+	}
+	b.WriteString(`    <dc:publisher>Diffbot</dc:publisher>` + "\n")
+	b.WriteString(`    <meta property="dcterms:modified">` + p.modified.UTC().Format("2006-01-02T15:04:05Z") + `</meta>` + "\n")
+	if img := p.coverImage(); img != nil {
+		b.WriteString(`    <meta name="cover" content="` + img.id + `"/>` + "\n")
+	}
+	b.WriteString(`  </metadata>` + "\n")
+
+	b.WriteString(`  <manifest>` + "\n")
+	b.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	b.WriteString(`    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>` + "\n")
+	b.WriteString(`    <item id="style" href="style.css" media-type="text/css"/>` + "\n")
+	if p.coverImage() != nil {
+		b.WriteString(`    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	for _, chapter := range p.chapters {
+		b.WriteString(`    <item id="` + chapter.id + `" href="` + chapter.href + `" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	for _, url := range p.order {
+		img := p.images[url]
+		var properties string
+		if url == p.coverUrl {
+			properties = ` properties="cover-image"`
+		}
+		b.WriteString(`    <item id="` + img.id + `" href="` + img.href + `" media-type="` + img.mediaType + `"` + properties + `/>` + "\n")
+	}
+	b.WriteString(`  </manifest>` + "\n")
+
+	b.WriteString(`  <spine toc="ncx">` + "\n")
+	if p.coverImage() != nil {
+		b.WriteString(`    <itemref idref="cover" linear="no"/>` + "\n")
+	}
+	for _, chapter := range p.chapters {
+		b.WriteString(`    <itemref idref="` + chapter.id + `"/>` + "\n")
+	}
+	b.WriteString(`  </spine>` + "\n")
+	b.WriteString(`</package>` + "\n")
+	return []byte(b.String())
+}
+
+func (p *book) chapterTitle(chapter *bookChapter) string {
+	if chapter.article.Title != "" {
+		return chapter.article.Title
+	}
+	if chapter.article.Url != "" {
+		return chapter.article.Url
+	}
+	return chapter.id
+}
+
+func (p *book) xhtmlHeader(b *strings.Builder, title string) {
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE html>` + "\n")
+	b.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + esc(p.language) + `" lang="` + esc(p.language) + `">` + "\n")
+	b.WriteString(`<head>` + "\n")
+	b.WriteString(`  <meta charset="UTF-8"/>` + "\n")
+	b.WriteString(`  <title>` + esc(title) + `</title>` + "\n")
+	b.WriteString(`  <link rel="stylesheet" type="text/css" href="style.css"/>` + "\n")
+	b.WriteString(`</head>` + "\n")
+}
+
+func (p *book) navDocument() []byte {
+	var b strings.Builder
+	p.xhtmlHeader(&b, p.title)
+	b.WriteString(`<body>` + "\n")
+	b.WriteString(`  <nav epub:type="toc" id="toc">` + "\n")
+	b.WriteString(`    <h1>` + esc(p.title) + `</h1>` + "\n")
+	b.WriteString(`    <ol>` + "\n")
+	for _, chapter := range p.chapters {
+		b.WriteString(`      <li><a href="` + chapter.href + `">` + esc(p.chapterTitle(chapter)) + `</a></li>` + "\n")
+	}
+	b.WriteString(`    </ol>` + "\n")
+	b.WriteString(`  </nav>` + "\n")
+	b.WriteString(`</body>` + "\n")
+	b.WriteString(`</html>` + "\n")
+	return []byte(b.String())
+}
+
+// ncxDocument returns the EPUB 2 table of contents, for the older readers.
+func (p *book) ncxDocument() []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">` + "\n")
+	b.WriteString(`  <head>` + "\n")
+	b.WriteString(`    <meta name="dtb:uid" content="` + esc(p.identifier) + `"/>` + "\n")
+	b.WriteString(`  </head>` + "\n")
+	b.WriteString(`  <docTitle><text>` + esc(p.title) + `</text></docTitle>` + "\n")
+	b.WriteString(`  <navMap>` + "\n")
+	for i, chapter := range p.chapters {
+		b.WriteString(fmt.Sprintf(`    <navPoint id="nav-%d" playOrder="%d">`, i+1, i+1) + "\n")
+		b.WriteString(`      <navLabel><text>` + esc(p.chapterTitle(chapter)) + `</text></navLabel>` + "\n")
+		b.WriteString(`      <content src="` + chapter.href + `"/>` + "\n")
+		b.WriteString(`    </navPoint>` + "\n")
+	}
+	b.WriteString(`  </navMap>` + "\n")
+	b.WriteString(`</ncx>` + "\n")
+	return []byte(b.String())
+}
+
+func (p *book) coverDocument() []byte {
+	var b strings.Builder
+	p.xhtmlHeader(&b, p.title)
+	b.WriteString(`<body>` + "\n")
+	b.WriteString(`  <section epub:type="cover">` + "\n")
+	b.WriteString(`    <figure><img src="` + p.coverImage().href + `" alt="` + esc(p.title) + `"/></figure>` + "\n")
+	b.WriteString(`  </section>` + "\n")
+	b.WriteString(`</body>` + "\n")
+	b.WriteString(`</html>` + "\n")
+	return []byte(b.String())
+}
+
+func (p *book) chapterDocument(chapter *bookChapter) []byte {
+	article := chapter.article
+	title := p.chapterTitle(chapter)
+
+	var b strings.Builder
+	p.xhtmlHeader(&b, title)
+	b.WriteString(`<body>` + "\n")
+	b.WriteString(`<section epub:type="chapter" id="` + chapter.id + `">` + "\n")
+	b.WriteString(`<h1>` + esc(title) + `</h1>` + "\n")
+
+	var byline []string
+	if article.Author != "" {
+		byline = append(byline, esc(article.Author))
+	}
+	if t, err := article.DateTime(); err == nil {
+		byline = append(byline, `<time datetime="`+t.UTC().Format(time.RFC3339)+`">`+t.Format("January 2, 2006")+`</time>`)
+	}
+	if len(byline) != 0 {
+		b.WriteString(`<p class="byline">` + strings.Join(byline, ", ") + `</p>` + "\n")
+	}
+
+	inline := make(map[string]bool)
+	content := article.SanitizedHTML(sanitizePolicy(func(tag, attr, url string) string {
+		if tag != "img" || attr != "src" {
+			return url
+		}
+		inline[url] = true
+		return p.imageHref(url)
+	}))
+	if strings.TrimSpace(content) == "" && article.Text != "" {
+		for _, line := range strings.Split(article.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				content += "<p>" + esc(line) + "</p>\n"
+			}
+		}
+	}
+	b.WriteString(strings.TrimSpace(content) + "\n")
+
+	// The images of Article.Images which are not in the html.
+	for _, img := range article.Images {
+		if inline[img.Url] || p.imageHref(img.Url) == "" {
+			continue
+		}
+		inline[img.Url] = true
+		b.WriteString(`<figure><img src="` + p.imageHref(img.Url) + `" alt="` + esc(img.Caption) + `"/>`)
+		if img.Caption != "" {
+			b.WriteString(`<figcaption>` + esc(img.Caption) + `</figcaption>`)
+		}
+		b.WriteString(`</figure>` + "\n")
+	}
+
+	if article.Url != "" {
+		b.WriteString(`<p class="source"><a href="` + esc(article.Url) + `">` + esc(article.Url) + `</a></p>` + "\n")
+	}
+	b.WriteString(`</section>` + "\n")
+	b.WriteString(`</body>` + "\n")
+	b.WriteString(`</html>` + "\n")
+	return []byte(b.String())
+}
+
+func esc(s string) string {
+	return html.EscapeString(s)
+}
@@ -0,0 +1,283 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diffbot/diffbot-go-client"
+)
+
+func testPng(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testArticles(server string) []*diffbot.Article {
+	return []*diffbot.Article{
+		{
+			Url:           server + "/posts/1.html",
+			Title:         "First & Best",
+			Author:        "John Davi",
+			Date:          "Wed, 31 Jul 2013 07:00:00 GMT",
+			HumanLanguage: "en",
+			Html: `<p>Hello <img src="/img/a.png" alt="a"></p>
+<script>alert(1)</script><iframe src="http://www.youtube.com/embed/x"></iframe>
+<p>Missing <img src="/img/missing.png"> and text&nbsp;image <img src="/img/text.png"></p>`,
+			Images: []diffbot.ArticleImage{
+				{Url: server + "/img/b.png", Caption: "Bee", Primary: "true"},
+				{Url: server + "/img/a.png"},
+			},
+		},
+		{
+			Url:    server + "/posts/2.html",
+			Title:  "Second",
+			Author: "Jane Roe",
+			Date:   "Thu, 01 Aug 2013 07:00:00 GMT",
+			Text:   "Line one.\nLine two.",
+		},
+	}
+}
+
+func testServer(t *testing.T) *httptest.Server {
+	img := testPng(t)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img/a.png", "/img/b.png":
+			w.Write(img)
+		case "/img/text.png":
+			w.Write([]byte("not an image"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func readZip(t *testing.T, data []byte) (*zip.Reader, map[string][]byte) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = d
+	}
+	return r, files
+}
+
+// testWellFormed checks the XML document is well-formed.
+func testWellFormed(t *testing.T, name string, data []byte) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", name, err, data)
+		}
+	}
+}
+
+type testPackage struct {
+	Version  string `xml:"version,attr"`
+	UniqueId string `xml:"unique-identifier,attr"`
+	Metadata struct {
+		Identifier string   `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+		Title      string   `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Language   string   `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Creator    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Date       string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Meta       []struct {
+			Property string `xml:"property,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		Id         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IdRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func TestWrite(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	modified := time.Date(2014, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := Write(&buf, testArticles(server.URL), &Options{Modified: modified}); err != nil {
+		t.Fatal(err)
+	}
+	r, files := readZip(t, buf.Bytes())
+
+	// OCF: the mimetype is the first entry, stored and without extra field.
+	first := r.File[0]
+	if a, b := first.Name, "mimetype"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := first.Method, zip.Store; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if len(first.Extra) != 0 {
+		t.Fatalf("mimetype has extra field: %x", first.Extra)
+	}
+	if a, b := string(files["mimetype"]), "application/epub+zip"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if !bytes.HasPrefix(buf.Bytes()[30:], []byte("mimetypeapplication/epub+zip")) {
+		t.Fatalf("invalid mimetype entry")
+	}
+	if !strings.Contains(string(files["META-INF/container.xml"]), `full-path="OEBPS/content.opf"`) {
+		t.Fatalf("invalid container.xml: %s", files["META-INF/container.xml"])
+	}
+	for name, data := range files {
+		if strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".opf") ||
+			strings.HasSuffix(name, ".xhtml") || strings.HasSuffix(name, ".ncx") {
+			testWellFormed(t, name, data)
+		}
+	}
+
+	var opf testPackage
+	if err := xml.Unmarshal(files["OEBPS/content.opf"], &opf); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := opf.Version, "3.0"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if !strings.HasPrefix(opf.Metadata.Identifier, "urn:uuid:") {
+		t.Fatalf("invalid identifier: %v", opf.Metadata.Identifier)
+	}
+	if a, b := opf.Metadata.Title, "First & Best"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := opf.Metadata.Language, "en"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := strings.Join(opf.Metadata.Creator, ","), "John Davi,Jane Roe"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := opf.Metadata.Date, "2013-08-01T07:00:00Z"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	var hasModified bool
+	for _, meta := range opf.Metadata.Meta {
+		if meta.Property == "dcterms:modified" {
+			hasModified = meta.Value == "2014-01-02T03:04:05Z"
+		}
+	}
+	if !hasModified {
+		t.Fatalf("invalid dcterms:modified: %v", opf.Metadata.Meta)
+	}
+
+	// The manifest items exist, and the images are a.png and b.png only.
+	var images, covers, navs int
+	for _, item := range opf.Manifest {
+		if _, ok := files["OEBPS/"+item.Href]; !ok {
+			t.Fatalf("missing manifest item: %v", item.Href)
+		}
+		if strings.HasPrefix(item.MediaType, "image/") {
+			images++
+		}
+		switch item.Properties {
+		case "cover-image":
+			covers++
+		case "nav":
+			navs++
+		}
+	}
+	if images != 2 || covers != 1 || navs != 1 {
+		t.Fatalf("invalid manifest: images = %d, covers = %d, navs = %d", images, covers, navs)
+	}
+	var spine []string
+	for _, item := range opf.Spine {
+		spine = append(spine, item.IdRef)
+	}
+	if a, b := strings.Join(spine, ","), "cover,chapter-001,chapter-002"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	nav := string(files["OEBPS/nav.xhtml"])
+	for _, s := range []string{`epub:type="toc"`, `<a href="chapter-001.xhtml">First &amp; Best</a>`, `<a href="chapter-002.xhtml">Second</a>`} {
+		if !strings.Contains(nav, s) {
+			t.Fatalf("nav.xhtml: missing %q", s)
+		}
+	}
+
+	chapter := string(files["OEBPS/chapter-001.xhtml"])
+	for _, s := range []string{`<img src="images/002.png" alt="a"/>`, `<figcaption>Bee</figcaption>`, "text\u00a0image"} {
+		if !strings.Contains(chapter, s) {
+			t.Fatalf("chapter-001.xhtml: missing %q\n%s", s, chapter)
+		}
+	}
+	for _, s := range []string{"<script", "<iframe", "missing.png", "text.png", server.URL + "/img/"} {
+		if strings.Contains(chapter, s) {
+			t.Fatalf("chapter-001.xhtml: unexpected %q\n%s", s, chapter)
+		}
+	}
+	if !strings.Contains(string(files["OEBPS/chapter-002.xhtml"]), "<p>Line two.</p>") {
+		t.Fatalf("chapter-002.xhtml: missing text\n%s", files["OEBPS/chapter-002.xhtml"])
+	}
+	for _, item := range opf.Manifest {
+		if item.Properties == "cover-image" && path.Base(item.Href) != "001.png" {
+			t.Fatalf("invalid cover: %v", item.Href)
+		}
+	}
+}
+
+func TestWrite_noImages(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, testArticles(server.URL), &Options{NoImages: true, Title: "Pack"}); err != nil {
+		t.Fatal(err)
+	}
+	_, files := readZip(t, buf.Bytes())
+	for name := range files {
+		if strings.HasPrefix(name, "OEBPS/images/") || name == "OEBPS/cover.xhtml" {
+			t.Fatalf("unexpected file: %v", name)
+		}
+	}
+	if strings.Contains(string(files["OEBPS/chapter-001.xhtml"]), "<img") {
+		t.Fatalf("unexpected image")
+	}
+	if !strings.Contains(string(files["OEBPS/content.opf"]), "<dc:title>Pack</dc:title>") {
+		t.Fatalf("invalid title")
+	}
+}
+
+func TestWrite_noArticles(t *testing.T) {
+	if err := Write(io.Discard, nil, nil); err == nil {
+		t.Fatalf("expect error")
+	}
+}
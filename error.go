@@ -5,7 +5,30 @@
 package diffbot
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Sentinel errors, test them with errors.Is:
+//
+//	article, err := diffbot.ParseArticle(token, url, nil)
+//	if errors.Is(err, diffbot.ErrRateLimited) {
+//		// slow down
+//	}
+var (
+	ErrUnauthorized = errors.New("diffbot: unauthorized token")
+	ErrNotFound     = errors.New("diffbot: requested page not found")
+	ErrRateLimited  = errors.New("diffbot: too many calls or throttled")
+	ErrProcessing   = errors.New("diffbot: error processing the page")
+	ErrTimeout      = errors.New("diffbot: timeout")
 )
 
 // Error represents an Diffbot APIs returns error.
@@ -30,10 +53,17 @@ import (
 //	| 500  | Error processing the page. Specific information will be returned in the JSON response.              |
 //	+------+-----------------------------------------------------------------------------------------------------+
 //
+// The HttpStatus, Header and RequestUrl fields are filled from the HTTP
+// exchange, the token in RequestUrl is redacted. The JSON fields other than
+// "error" and "errorCode" (e.g. "errorAnalysis") are kept in Extra.
 type Error struct {
-	ErrCode    int    `json:"errorCode"` // Description of the error
-	ErrMessage string `json:"error"`     // Error code per the chart below
-	RawString  string `json:"-"`         // Raw json format error string
+	ErrCode    int                        `json:"errorCode"` // Description of the error
+	ErrMessage string                     `json:"error"`     // Error code per the chart below
+	RawString  string                     `json:"-"`         // Raw json format error string
+	HttpStatus int                        `json:"-"`         // HTTP status code of the response
+	Header     http.Header                `json:"-"`         // HTTP headers of the response
+	RequestUrl string                     `json:"-"`         // Request URL with the token redacted
+	Extra      map[string]json.RawMessage `json:"-"`         // Unrecognised JSON fields
 }
 
 // ParseJson parses the JSON-encoded error data.
@@ -41,11 +71,170 @@ func (p *Error) ParseJson(s string) error {
 	if err := json.Unmarshal([]byte(s), p); err != nil {
 		return err
 	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &fields); err != nil {
+		return err
+	}
+	delete(fields, "errorCode")
+	delete(fields, "error")
+	p.Extra = nil
+	if len(fields) != 0 {
+		p.Extra = fields
+	}
 	p.RawString = s
 	return nil
 }
 
+// RetryAfter returns the delay from the Retry-After response header.
+func (p *Error) RetryAfter() (d time.Duration, ok bool) {
+	v := p.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d = time.Until(t); d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
 func (p *Error) Error() string {
 	d, _ := json.Marshal(p)
 	return string(d)
 }
+
+// Is maps the error code to the sentinel errors:
+//
+//	+----------+-----------------+
+//	| CODE     | SENTINEL        |
+//	+----------+-----------------+
+//	| 401, 403 | ErrUnauthorized |
+//	| 404      | ErrNotFound     |
+//	| 429      | ErrRateLimited  |
+//	| 500      | ErrProcessing   |
+//	| 408, 504 | ErrTimeout      |
+//	+----------+-----------------+
+//
+// An error whose message reports a timeout also matches ErrTimeout.
+func (p *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return p.ErrCode == 401 || p.ErrCode == 403
+	case ErrNotFound:
+		return p.ErrCode == 404
+	case ErrRateLimited:
+		return p.ErrCode == 429
+	case ErrProcessing:
+		return p.ErrCode == 500
+	case ErrTimeout:
+		if p.ErrCode == 408 || p.ErrCode == 504 {
+			return true
+		}
+		msg := strings.ToLower(p.ErrMessage)
+		return strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out")
+	}
+	return false
+}
+
+// Retryable reports whether the same call may succeed later.
+func (p *Error) Retryable() bool {
+	switch {
+	case p.ErrCode == 408 || p.ErrCode == 429:
+		return true
+	case p.ErrCode >= 500 && p.ErrCode != 501:
+		return true
+	}
+	return false
+}
+
+// Temporary is an alias of Retryable.
+func (p *Error) Temporary() bool {
+	return p.Retryable()
+}
+
+// TransportError represents a failure to send the request
+// or to read the response, the Diffbot API was not reached.
+type TransportError struct {
+	Method string // Diffbot method, e.g. "article"
+	Err    error
+}
+
+func (p *TransportError) Error() string {
+	return redactToken("diffbot: " + p.Method + ": " + p.Err.Error())
+}
+
+func (p *TransportError) Unwrap() error {
+	return p.Err
+}
+
+// Is matches ErrTimeout if the underlying error is a timeout.
+func (p *TransportError) Is(target error) bool {
+	if target != ErrTimeout {
+		return false
+	}
+	if errors.Is(p.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(p.Err, &netErr) && netErr.Timeout()
+}
+
+// Retryable reports whether the same call may succeed later, i.e. the
+// underlying error is a timeout, a temporary network error, or a reset or
+// closed connection. The invalid URLs, the TLS certificate failures and the
+// unknown hosts are not retryable.
+func (p *TransportError) Retryable() bool {
+	if errors.Is(p.Err, context.Canceled) {
+		return false
+	}
+	if p.Is(ErrTimeout) {
+		return true
+	}
+	if errors.Is(p.Err, syscall.ECONNRESET) || errors.Is(p.Err, io.EOF) || errors.Is(p.Err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr interface{ Temporary() bool }
+	return errors.As(p.Err, &netErr) && netErr.Temporary()
+}
+
+// Temporary is an alias of Retryable.
+func (p *TransportError) Temporary() bool {
+	return p.Retryable()
+}
+
+// DecodeError represents a response body which can not be decoded
+// into the result type.
+type DecodeError struct {
+	Method string // Diffbot method, e.g. "article"
+	Err    error
+}
+
+func (p *DecodeError) Error() string {
+	return "diffbot: " + p.Method + ": decode: " + p.Err.Error()
+}
+
+func (p *DecodeError) Unwrap() error {
+	return p.Err
+}
+
+// Retryable always returns false.
+func (p *DecodeError) Retryable() bool {
+	return false
+}
+
+// Temporary is an alias of Retryable.
+func (p *DecodeError) Temporary() bool {
+	return false
+}
+
+// IsRetryable reports whether err, or any error it wraps,
+// is a retryable Diffbot error.
+func IsRetryable(err error) bool {
+	var e interface{ Retryable() bool }
+	return errors.As(err, &e) && e.Retryable()
+}
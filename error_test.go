@@ -5,7 +5,21 @@
 package diffbot
 
 import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestError(t *testing.T) {
@@ -23,12 +37,64 @@ func TestError(t *testing.T) {
 		if a, b := testErrors[i].str, e.RawString; a != b {
 			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
 		}
+		if a, b := testErrors[i].extra, len(e.Extra); a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+	}
+
+	var analysis struct {
+		Blame      string `json:"blame"`
+		ReasonCode int    `json:"reasonCode"`
+	}
+	if err := json.Unmarshal(e.Extra["errorAnalysis"], &analysis); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := 817221, analysis.ReasonCode; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := `"Use a robot."`, string(e.Extra["suggestedImprovement"]); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestError_http(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `<html>Too Many Requests</html>`)
+	}))
+	defer ts.Close()
+
+	_, err := DiffbotServer(ts.URL, "article", "secret", "http://example.com/", nil)
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expect *Error, got = %#v", err)
+	}
+	if a, b := 429, apiErr.ErrCode; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 429, apiErr.HttpStatus; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := `<html>Too Many Requests</html>`, apiErr.ErrMessage; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := "0", apiErr.Header.Get("X-RateLimit-Remaining"); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if d, ok := apiErr.RetryAfter(); !ok || d != 2*time.Minute {
+		t.Fatalf("expect = %v, got = %v", 2*time.Minute, d)
+	}
+	if a, b := ts.URL+"/article?token=REDACTED&url=http%3A%2F%2Fexample.com%2F", apiErr.RequestUrl; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
 	}
 }
 
 var testErrors = []struct {
-	str string
-	err Error
+	str   string
+	err   Error
+	extra int
 }{
 	{
 		str: `
@@ -57,5 +123,108 @@ var testErrors = []struct {
 			ErrCode:    404,
 			ErrMessage: "Something went crazy wrong.",
 		},
+		extra: 2,
 	},
 }
+
+func TestError_is(t *testing.T) {
+	sentinels := []error{ErrUnauthorized, ErrNotFound, ErrRateLimited, ErrProcessing, ErrTimeout}
+	for i, v := range []struct {
+		err       *Error
+		is        error
+		retryable bool
+	}{
+		{err: &Error{ErrCode: 401}, is: ErrUnauthorized},
+		{err: &Error{ErrCode: 404}, is: ErrNotFound},
+		{err: &Error{ErrCode: 429}, is: ErrRateLimited, retryable: true},
+		{err: &Error{ErrCode: 500}, is: ErrProcessing, retryable: true},
+		{err: &Error{ErrCode: 504}, is: ErrTimeout, retryable: true},
+		{err: &Error{ErrCode: 400, ErrMessage: "Request timed out"}, is: ErrTimeout},
+		{err: &Error{ErrCode: 400}},
+	} {
+		for _, target := range sentinels {
+			if a, b := target == v.is, errors.Is(v.err, target); a != b {
+				t.Fatalf("%d: errors.Is(%v, %v), expect = %v, got = %v", i, v.err, target, a, b)
+			}
+		}
+		if a, b := v.retryable, v.err.Retryable(); a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+		if a, b := v.retryable, IsRetryable(fmt.Errorf("wrapped: %w", v.err)); a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second / 10)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := DiffbotServerContext(ctx, ts.URL, "article", "secret", "http://example.com/", nil)
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("expect TransportError, got = %#v", err)
+	}
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect timeout, got = %v", err)
+	}
+	if !IsRetryable(err) {
+		t.Fatalf("expect retryable, got = %v", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatalf("token not redacted: %v", err)
+	}
+
+	err = &TransportError{Method: "article", Err: context.Canceled}
+	if IsRetryable(err) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("expect not retryable, got = %v", err)
+	}
+}
+
+func TestTransportError_retryable(t *testing.T) {
+	for i, v := range []struct {
+		err       error
+		retryable bool
+	}{
+		{err: io.EOF, retryable: true},
+		{err: &url.Error{Op: "Get", URL: "http://a.com", Err: io.ErrUnexpectedEOF}, retryable: true},
+		{err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, retryable: true},
+		{err: &net.DNSError{Err: "i/o timeout", Name: "a.com", IsTimeout: true}, retryable: true},
+		{err: &net.DNSError{Err: "server misbehaving", Name: "a.com", IsTemporary: true}, retryable: true},
+		{err: &net.DNSError{Err: "no such host", Name: "a.com", IsNotFound: true}},
+		{err: &url.Error{Op: "Get", URL: "http://a.com", Err: x509.UnknownAuthorityError{}}},
+		{err: &url.Error{Op: "parse", URL: "http://a b.com", Err: errors.New("invalid character \" \" in host name")}},
+		{err: fmt.Errorf("wrapped: %w", context.Canceled)},
+	} {
+		err := &TransportError{Method: "article", Err: v.err}
+		if a, b := v.retryable, err.Retryable(); a != b {
+			t.Fatalf("%d: %v: expect = %v, got = %v", i, err, a, b)
+		}
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"article","title":}`)
+	}))
+	defer ts.Close()
+
+	_, err := ParseArticle("token", "http://example.com/", &Options{Client: testRedirectDoer(ts.URL)})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expect DecodeError, got = %#v", err)
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expect json.SyntaxError, got = %#v", decodeErr.Err)
+	}
+	if IsRetryable(err) {
+		t.Fatalf("expect not retryable, got = %v", err)
+	}
+}
@@ -0,0 +1,485 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	urlPkg "net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Feed is a list of entries built from the extracted articles or a
+// frontpage, which can be encoded as RSS 2.0, Atom 1.0 or JSON Feed 1.1.
+type Feed struct {
+	Title       string
+	Link        string // The URL of the site
+	Description string
+	Icon        string
+	Language    string    // e.g. "en"
+	Updated     time.Time // The latest Published of Items if zero
+	Items       []FeedItem
+}
+
+// FeedItem is an entry of the Feed.
+type FeedItem struct {
+	Id        string // The Link if empty
+	Title     string
+	Link      string
+	Summary   string // Plain text
+	Content   string // HTML
+	Author    string
+	Image     string
+	Tags      []string
+	Published time.Time
+}
+
+// feedSummaryLength is the max length of the summary made from the article text.
+const feedSummaryLength = 280
+
+// NewArticleFeed returns a feed of the articles. The article content is
+// the Article.SanitizedHTML, and the summary is the beginning of the text.
+func NewArticleFeed(title, link string, articles []*Article) *Feed {
+	feed := &Feed{Title: title, Link: link}
+	for _, article := range articles {
+		if feed.Language == "" {
+			feed.Language = article.HumanLanguage
+		}
+		item := FeedItem{
+			Id:      article.ResolvedUrl,
+			Title:   article.Title,
+			Link:    article.Url,
+			Summary: feedSummary(article.Text),
+			Content: strings.TrimSpace(article.SanitizedHTML(nil)),
+			Author:  article.Author,
+			Tags:    article.Tags,
+		}
+		if item.Id == "" {
+			item.Id = article.Url
+		}
+		if article.ResolvedUrl != "" {
+			item.Link = article.ResolvedUrl
+		}
+		if img := article.PrimaryImage(); img != nil {
+			item.Image = img.Url
+		}
+		if t, err := article.DateTime(); err == nil {
+			item.Published = t
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+// NewFrontpageFeed returns a feed of the frontpage items. The item content
+// is the item Description sanitized with the DefaultSanitizePolicy, the
+// relative links are resolved against the frontpage SourceURL.
+//
+// The items without link are skipped, use Frontpage.Items to filter the
+// items by type or spam score first.
+func NewFrontpageFeed(page *Frontpage) *Feed {
+	feed := &Feed{
+		Title: page.Title,
+		Link:  page.SourceURL,
+		Icon:  page.Icon,
+	}
+	base, err := urlPkg.Parse(page.SourceURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+	for _, v := range page.Items {
+		if v.Link == "" {
+			continue
+		}
+		item := FeedItem{
+			Title:   v.Title,
+			Link:    v.Link,
+			Summary: v.TextSummary,
+			Content: strings.TrimSpace(sanitizeHTML(v.Description, base, nil)),
+			Image:   v.Img,
+		}
+		if t, err := v.PubDateTime(); err == nil {
+			item.Published = t
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+func feedSummary(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= feedSummaryLength {
+		return text
+	}
+	runes := []rune(text)[:feedSummaryLength]
+	if i := strings.LastIndex(string(runes), " "); i > 0 {
+		return string(runes)[:i] + "…"
+	}
+	return string(runes) + "…"
+}
+
+func (p *Feed) updated() time.Time {
+	if !p.Updated.IsZero() {
+		return p.Updated
+	}
+	var updated time.Time
+	for _, item := range p.Items {
+		if item.Published.After(updated) {
+			updated = item.Published
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return updated
+}
+
+func (p *FeedItem) id() string {
+	if p.Id != "" {
+		return p.Id
+	}
+	return p.Link
+}
+
+// description returns the description of the feed, which is required
+// by RSS.
+func (p *Feed) description() string {
+	if p.Description != "" {
+		return p.Description
+	}
+	return p.Title
+}
+
+// author returns the host of the feed link, used by Atom when an entry
+// has no author.
+func (p *Feed) author() string {
+	if u, err := urlPkg.Parse(p.Link); err == nil && u.Host != "" {
+		return u.Host
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+	return "unknown"
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Image         *rssImage `xml:"image"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssImage struct {
+	Url   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title,omitempty"`
+	Link        string        `xml:"link,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Content     *xmlCData     `xml:"content:encoded"`
+	Categories  []string      `xml:"category"`
+	Guid        *rssGuid      `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	Url    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type xmlCData struct {
+	Value string `xml:",cdata"`
+}
+
+// feedGenerator is the generator name of the encoded feeds.
+const feedGenerator = "diffbot-go-client"
+
+// WriteRSS writes the feed as a RSS 2.0 document.
+//
+// The item content is written as content:encoded, and the item image as
+// an enclosure.
+func (p *Feed) WriteRSS(w io.Writer) error {
+	rss := rssFeed{
+		Version: "2.0",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		Channel: rssChannel{
+			Title:         p.Title,
+			Link:          p.Link,
+			Description:   p.description(),
+			Language:      p.Language,
+			LastBuildDate: p.updated().Format(time.RFC1123Z),
+			Generator:     feedGenerator,
+		},
+	}
+	if p.Icon != "" {
+		rss.Channel.Image = &rssImage{Url: p.Icon, Title: p.Title, Link: p.Link}
+	}
+	for _, v := range p.Items {
+		item := rssItem{
+			Title:       v.Title,
+			Link:        v.Link,
+			Description: v.Summary,
+			Categories:  v.Tags,
+		}
+		if item.Title == "" && item.Description == "" {
+			// RSS requires one of title or description.
+			item.Title = v.Link
+		}
+		if v.Content != "" {
+			item.Content = &xmlCData{Value: v.Content}
+		}
+		if id := v.id(); id != "" {
+			item.Guid = &rssGuid{IsPermaLink: id == v.Link, Value: id}
+		}
+		if !v.Published.IsZero() {
+			item.PubDate = v.Published.Format(time.RFC1123Z)
+		}
+		if v.Image != "" {
+			item.Enclosure = &rssEnclosure{Url: v.Image, Type: feedImageType(v.Image)}
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+	return writeXml(w, rss)
+}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang      string      `xml:"xml:lang,attr,omitempty"`
+	Id        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomPerson `xml:"author"`
+	Icon      string      `xml:"icon,omitempty"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Id         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomPerson    `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteAtom writes the feed as an Atom 1.0 document.
+//
+// The entries without published time use the feed updated time, and the
+// entries without author use the host of the feed link. The entries
+// without id and link use the feed id with the entry index as fragment.
+func (p *Feed) WriteAtom(w io.Writer) error {
+	updated := p.updated().UTC().Format(time.RFC3339)
+	atom := atomFeed{
+		Lang:      p.Language,
+		Id:        p.Link,
+		Title:     p.Title,
+		Subtitle:  p.Description,
+		Updated:   updated,
+		Icon:      p.Icon,
+		Generator: feedGenerator,
+		Author:    &atomPerson{Name: p.author()},
+	}
+	if atom.Id == "" {
+		atom.Id = "urn:diffbot:feed:" + urlPkg.PathEscape(p.Title)
+	}
+	if p.Link != "" {
+		atom.Links = []atomLink{{Rel: "alternate", Href: p.Link}}
+	}
+	for i, v := range p.Items {
+		entry := atomEntry{
+			Id:      v.id(),
+			Title:   v.Title,
+			Updated: updated,
+		}
+		if entry.Id == "" {
+			entry.Id = atom.Id + "#" + strconv.Itoa(i)
+		}
+		if !v.Published.IsZero() {
+			entry.Updated = v.Published.UTC().Format(time.RFC3339)
+			entry.Published = entry.Updated
+		}
+		if v.Link != "" {
+			entry.Links = append(entry.Links, atomLink{Rel: "alternate", Href: v.Link})
+		}
+		if v.Image != "" {
+			entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Href: v.Image, Type: feedImageType(v.Image)})
+		}
+		if v.Author != "" {
+			entry.Author = &atomPerson{Name: v.Author}
+		}
+		for _, tag := range v.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		if v.Summary != "" {
+			entry.Summary = &atomText{Type: "text", Value: v.Summary}
+		}
+		if v.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: v.Content}
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+	return writeXml(w, atom)
+}
+
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageUrl string           `json:"home_page_url,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Icon        string           `json:"icon,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedItem struct {
+	Id            string           `json:"id"`
+	Url           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHtml   string           `json:"content_html,omitempty"`
+	ContentText   *string          `json:"content_text,omitempty"` // Set if ContentHtml is empty
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// JSONFeedVersion is the version URL of the JSON Feed written by WriteJSONFeed.
+const JSONFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// WriteJSONFeed writes the feed as a JSON Feed 1.1 document.
+func (p *Feed) WriteJSONFeed(w io.Writer) error {
+	feed := jsonFeed{
+		Version:     JSONFeedVersion,
+		Title:       p.Title,
+		HomePageUrl: p.Link,
+		Description: p.Description,
+		Icon:        p.Icon,
+		Language:    p.Language,
+		Items:       []jsonFeedItem{},
+	}
+	for i, v := range p.Items {
+		item := jsonFeedItem{
+			Id:          v.id(),
+			Url:         v.Link,
+			Title:       v.Title,
+			ContentHtml: v.Content,
+			Summary:     v.Summary,
+			Image:       v.Image,
+			Tags:        v.Tags,
+		}
+		if item.Id == "" {
+			item.Id = strconv.Itoa(i)
+		}
+		if item.ContentHtml == "" {
+			// JSON Feed requires one of content_html or content_text,
+			// the summary, title or link is used, even if it is empty.
+			text := v.Summary
+			for _, s := range []string{v.Title, v.Link} {
+				if text == "" {
+					text = s
+				}
+			}
+			item.ContentText = &text
+		}
+		if !v.Published.IsZero() {
+			item.DatePublished = v.Published.Format(time.RFC3339)
+		}
+		if v.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: v.Author}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(feed)
+}
+
+func writeXml(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// feedImageType guesses the MIME type of the image from the URL extension.
+func feedImageType(url string) string {
+	if u, err := urlPkg.Parse(url); err == nil {
+		url = u.Path
+	}
+	switch strings.ToLower(path.Ext(url)) {
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".svg":
+		return "image/svg+xml"
+	}
+	return "image/jpeg"
+}
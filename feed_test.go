@@ -0,0 +1,302 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeeds(t *testing.T) map[string]*Feed {
+	var article Article
+	if err := article.ParseJson([]byte(testJsonDataArticle)); err != nil {
+		t.Fatal(err)
+	}
+	var dml FrontpageDML
+	if err := dml.ParseJson([]byte(testJsonDataFrontpage)); err != nil {
+		t.Fatal(err)
+	}
+	var page Frontpage
+	if err := page.ParseDML(&dml); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]*Feed{
+		"article":   NewArticleFeed("Diffblog", "http://blog.diffbot.com/", []*Article{&article}),
+		"frontpage": NewFrontpageFeed(&page),
+	}
+}
+
+func TestFeed_articles(t *testing.T) {
+	feed := testFeeds(t)["article"]
+	if a, b := len(feed.Items), 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	item := feed.Items[0]
+	if a, b := item.Link, "http://blog.diffbot.com/diffbots-new-product-api-teaches-robots-to-shop-online/"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := item.Author, "John Davi"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := item.Published, time.Date(2013, 7, 31, 7, 0, 0, 0, time.UTC); !a.Equal(b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := feed.Language, "en"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if n := len([]rune(item.Summary)); n == 0 || n > feedSummaryLength+1 {
+		t.Fatalf("invalid summary length: %d", n)
+	}
+	if strings.Contains(item.Content, "<iframe") {
+		t.Fatalf("content is not sanitized: %q", item.Content)
+	}
+}
+
+func TestFeed_frontpage(t *testing.T) {
+	feed := testFeeds(t)["frontpage"]
+	if a, b := feed.Title, testGoldenFrontpage.Title; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if len(feed.Items) == 0 {
+		t.Fatalf("no items")
+	}
+	for i, item := range testGoldenFrontpageItems {
+		if a, b := feed.Items[i].Link, item.Link; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, b, a)
+		}
+		if a, b := feed.Items[i].Published.Format(time.RFC1123), item.PubDate; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, b, a)
+		}
+	}
+}
+
+func TestFeed_frontpageSanitized(t *testing.T) {
+	page := &Frontpage{
+		Title:     "News",
+		SourceURL: "http://example.com/news/",
+		Items: []FrontpageItem{{
+			Link:        "http://example.com/a.html",
+			Description: `<p onclick="x()">A <a href="b.html">story</a><script>alert(1)</script></p><iframe src="http://evil.com/"></iframe>`,
+		}},
+	}
+	feed := NewFrontpageFeed(page)
+	if a, b := feed.Items[0].Content, `<p>A <a href="http://example.com/news/b.html">story</a></p>`; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFeed_writeAtomFallbackId(t *testing.T) {
+	feed := &Feed{Title: "Notes", Items: []FeedItem{{Title: "One"}, {Title: "Two", Id: "urn:x:2"}}}
+	var buf bytes.Buffer
+	if err := feed.WriteAtom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var atom struct {
+		Id      string   `xml:"id"`
+		Entries []string `xml:"entry>id"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &atom); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := atom.Entries, []string{"urn:diffbot:feed:Notes#0", "urn:x:2"}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFeed_writeRSS(t *testing.T) {
+	for name, feed := range testFeeds(t) {
+		var buf bytes.Buffer
+		if err := feed.WriteRSS(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var rss struct {
+			XMLName xml.Name `xml:"rss"`
+			Version string   `xml:"version,attr"`
+			Channel struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				Description string `xml:"description"`
+				Items       []struct {
+					Title       string `xml:"title"`
+					Link        string `xml:"link"`
+					Description string `xml:"description"`
+					Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+					Guid        string `xml:"guid"`
+					PubDate     string `xml:"pubDate"`
+				} `xml:"item"`
+			} `xml:"channel"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &rss); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if a, b := rss.Version, "2.0"; a != b {
+			t.Fatalf("%s: expect = %v, got = %v", name, b, a)
+		}
+		if rss.Channel.Title == "" || rss.Channel.Link == "" || rss.Channel.Description == "" {
+			t.Fatalf("%s: missing channel elements: %+v", name, rss.Channel)
+		}
+		if a, b := len(rss.Channel.Items), len(feed.Items); a != b {
+			t.Fatalf("%s: expect = %v, got = %v", name, b, a)
+		}
+		for i, item := range rss.Channel.Items {
+			if item.Title == "" && item.Description == "" {
+				t.Fatalf("%s: %d: missing title and description", name, i)
+			}
+			if a, b := item.Guid, feed.Items[i].id(); a != b {
+				t.Fatalf("%s: %d: expect = %v, got = %v", name, i, b, a)
+			}
+			if a, b := item.Content, feed.Items[i].Content; a != b {
+				t.Fatalf("%s: %d: expect = %q, got = %q", name, i, b, a)
+			}
+			if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+				t.Fatalf("%s: %d: %v", name, i, err)
+			}
+		}
+	}
+}
+
+func TestFeed_writeAtom(t *testing.T) {
+	for name, feed := range testFeeds(t) {
+		var buf bytes.Buffer
+		if err := feed.WriteAtom(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var atom struct {
+			XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+			Id      string   `xml:"id"`
+			Title   string   `xml:"title"`
+			Updated string   `xml:"updated"`
+			Author  []string `xml:"author>name"`
+			Entries []struct {
+				Id      string   `xml:"id"`
+				Title   string   `xml:"title"`
+				Updated string   `xml:"updated"`
+				Author  []string `xml:"author>name"`
+				Links   []struct {
+					Rel  string `xml:"rel,attr"`
+					Href string `xml:"href,attr"`
+				} `xml:"link"`
+				Content struct {
+					Type  string `xml:"type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"content"`
+			} `xml:"entry"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &atom); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if atom.Id == "" || atom.Title == "" || len(atom.Author) == 0 {
+			t.Fatalf("%s: missing feed elements", name)
+		}
+		if _, err := time.Parse(time.RFC3339, atom.Updated); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if a, b := len(atom.Entries), len(feed.Items); a != b {
+			t.Fatalf("%s: expect = %v, got = %v", name, b, a)
+		}
+		for i, entry := range atom.Entries {
+			if entry.Id == "" {
+				t.Fatalf("%s: %d: missing id", name, i)
+			}
+			if _, err := time.Parse(time.RFC3339, entry.Updated); err != nil {
+				t.Fatalf("%s: %d: %v", name, i, err)
+			}
+			if len(entry.Links) == 0 || entry.Links[0].Rel != "alternate" || entry.Links[0].Href != feed.Items[i].Link {
+				t.Fatalf("%s: %d: invalid links: %v", name, i, entry.Links)
+			}
+			if a, b := entry.Content.Value, feed.Items[i].Content; a != b {
+				t.Fatalf("%s: %d: expect = %q, got = %q", name, i, b, a)
+			}
+		}
+	}
+}
+
+func TestFeed_writeJSONFeed(t *testing.T) {
+	for name, feed := range testFeeds(t) {
+		var buf bytes.Buffer
+		if err := feed.WriteJSONFeed(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var result struct {
+			Version string                   `json:"version"`
+			Title   string                   `json:"title"`
+			Items   []map[string]interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if a, b := result.Version, JSONFeedVersion; a != b {
+			t.Fatalf("%s: expect = %v, got = %v", name, b, a)
+		}
+		if result.Title == "" {
+			t.Fatalf("%s: missing title", name)
+		}
+		if a, b := len(result.Items), len(feed.Items); a != b {
+			t.Fatalf("%s: expect = %v, got = %v", name, b, a)
+		}
+		for i, item := range result.Items {
+			if id, _ := item["id"].(string); id == "" {
+				t.Fatalf("%s: %d: missing id", name, i)
+			}
+			if item["content_html"] == nil && item["content_text"] == nil {
+				t.Fatalf("%s: %d: missing content", name, i)
+			}
+			if s, ok := item["date_published"].(string); ok {
+				if _, err := time.Parse(time.RFC3339, s); err != nil {
+					t.Fatalf("%s: %d: %v", name, i, err)
+				}
+			}
+		}
+	}
+}
+
+func TestFeed_writeJSONFeedEmptyContent(t *testing.T) {
+	articles := []*Article{
+		{Title: "Only Title", Url: "http://a.com/1"},
+		{Url: "http://a.com/2"},
+		{},
+	}
+	feed := NewArticleFeed("Empty", "http://a.com/", articles)
+	var buf bytes.Buffer
+	if err := feed.WriteJSONFeed(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	var texts []interface{}
+	for _, item := range result.Items {
+		texts = append(texts, item["content_text"])
+	}
+	if a, b := texts, []interface{}{"Only Title", "http://a.com/2", ""}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFeed_empty(t *testing.T) {
+	feed := &Feed{Title: "Empty"}
+	var buf bytes.Buffer
+	if err := feed.WriteJSONFeed(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"items": []`) {
+		t.Fatalf("items should be an empty array: %s", buf.String())
+	}
+	buf.Reset()
+	if err := feed.WriteAtom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<id>urn:diffbot:feed:Empty</id>") {
+		t.Fatalf("missing feed id: %s", buf.String())
+	}
+}
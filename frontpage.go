@@ -5,50 +5,53 @@
 package diffbot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Frontpage represents a frontpage information.
 //
 // See http://diffbot.com/dev/docs/frontpage/
 type Frontpage struct {
-	Id        int64  `json:"id,string"`
-	Title     string `json:"title"`
-	SourceURL string `json:"sourceURL"`
-	Icon      string `json:"icon"`
-	NumItems  int    `json:"numItems"`
-	Items     []struct {
-		Id          int     `json:"id"`
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		XRoot       string  `json:"xroot"`
-		PubDate     string  `json:"pubDate"`
-		Link        string  `json:"link"`
-		Type        string  `json:"type"` // STORY/LINK/...
-		Img         string  `json:"img"`
-		TextSummary string  `json:"textSummary"`
-		Sp          float64 `json:"sp"`
-		Sr          float64 `json:"sr"`
-		Fresh       float64 `json:"fresh"`
-	} `json:"items,omitempty"`
+	Id           int64                      `json:"id,string"`
+	Title        string                     `json:"title"`
+	SourceURL    string                     `json:"sourceURL"`
+	SourceType   string                     `json:"sourceType,omitempty"` // e.g. "html"
+	Icon         string                     `json:"icon"`
+	NumItems     int                        `json:"numItems"`
+	NumSpamItems int                        `json:"numSpamItems,omitempty"`
+	Other        map[string]string          `json:"other,omitempty"` // Other child nodes of the info section.
+	Items        []FrontpageItem            `json:"items,omitempty"`
+	Warnings     []DecodeWarning            `json:"-"` // Mismatched fields of the DML.
+	Raw          json.RawMessage            `json:"-"` // The JSON data of the DML.
+	Extra        map[string]json.RawMessage `json:"-"` // Unrecognised JSON fields of the DML.
 }
 
-// type of Frontpage.Items[?]
-type frontpageItemType struct {
-	Id          int     `json:"id"`
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	XRoot       string  `json:"xroot"`
-	PubDate     string  `json:"pubDate"`
-	Link        string  `json:"link"`
-	Type        string  `json:"type"` // STORY/LINK/...
-	Img         string  `json:"img"`  // ?
-	TextSummary string  `json:"textSummary"`
-	Sp          float64 `json:"sp"`
-	Sr          float64 `json:"sr"`
-	Fresh       float64 `json:"fresh"`
+// FrontpageItem represents an item of the Frontpage.
+type FrontpageItem struct {
+	Id           int               `json:"id"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	XRoot        string            `json:"xroot"`
+	Cluster      string            `json:"cluster,omitempty"` // XPath of the DOM cluster of the item.
+	PubDate      string            `json:"pubDate"`
+	Link         string            `json:"link"`
+	Type         string            `json:"type"` // STORY/LINK/...
+	Img          string            `json:"img"`
+	TextSummary  string            `json:"textSummary"`
+	SourceType   string            `json:"sourceType,omitempty"`
+	Author       string            `json:"author,omitempty"`
+	CommentCount int               `json:"commentCount,omitempty"`
+	Sp           float64           `json:"sp"`
+	Sr           float64           `json:"sr"`
+	Fresh        float64           `json:"fresh"`
+	Attrs        map[string]string `json:"attrs,omitempty"` // Other attributes of the item.
+	Other        map[string]string `json:"other,omitempty"` // Other child nodes of the item.
 }
 
 // FrontpageDML (Diffbot Markup Language) is an XML format for encoding
@@ -73,16 +76,78 @@ type FrontpageDML struct {
 			TagName    string   `json:"tagName"` // title/sourceType/...
 			ChildNodes []string `json:"childNodes"`
 		} `json:"childNodes"`
+		ItemAttrs map[string]string `json:"-"` // item.*, the other attributes, see ParseJson.
 	} `json:"childNodes"`
+	Warnings []DecodeWarning            `json:"-"` // Mismatched fields, see ParseJson.
+	Raw      json.RawMessage            `json:"-"` // The JSON data, see ParseJson.
+	Extra    map[string]json.RawMessage `json:"-"` // Unrecognised JSON fields, see ParseJson.
 }
 
-func (p *FrontpageDML) ParseJson(data []byte) error {
-	if err := json.Unmarshal(data, p); err != nil {
+// type of FrontpageDML.ChildNodes[?]
+type frontpageDMLNodeType struct {
+	TagName          string `json:"tagName"`
+	ItemId           int64  `json:"id,string"`
+	ItemSp           string `json:"sp"`
+	ItemFresh        string `json:"fresh"`
+	ItemSr           string `json:"sr"`
+	ItemCluster      string `json:"cluster"`
+	ItemCommentCount int64  `json:"commentCount,string"`
+	ItemType         string `json:"type"`
+	ItemXRoot        string `json:"xroot"`
+	ChildNodes       []struct {
+		TagName    string   `json:"tagName"`
+		ChildNodes []string `json:"childNodes"`
+	} `json:"childNodes"`
+	ItemAttrs map[string]string `json:"-"`
+}
+
+// type of FrontpageDML.ChildNodes[?].ChildNodes[?]
+type frontpageDMLTextNodeType struct {
+	TagName    string   `json:"tagName"`
+	ChildNodes []string `json:"childNodes"`
+}
+
+// ParseJson parses the JSON-encoded DML data, see DecodeWarning. The
+// other string fields of the info and item nodes are kept in ItemAttrs.
+func (p *FrontpageDML) ParseJson(data []byte) (err error) {
+	if p.Warnings, p.Raw, p.Extra, err = decodeResultJson(data, p); err != nil {
 		return err
 	}
+	p.parseItemAttrs(data)
 	return nil
 }
 
+func (p *FrontpageDML) parseItemAttrs(data []byte) {
+	var dml struct {
+		ChildNodes []map[string]json.RawMessage `json:"childNodes"`
+	}
+	if err := json.Unmarshal(data, &dml); err != nil {
+		return
+	}
+	known := jsonFields(reflect.TypeOf(p.ChildNodes).Elem())
+	for i, node := range dml.ChildNodes {
+		if i >= len(p.ChildNodes) {
+			break
+		}
+	next:
+		for key, value := range node {
+			for name := range known {
+				if strings.EqualFold(key, name) {
+					continue next
+				}
+			}
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				continue
+			}
+			if p.ChildNodes[i].ItemAttrs == nil {
+				p.ChildNodes[i].ItemAttrs = make(map[string]string)
+			}
+			p.ChildNodes[i].ItemAttrs[key] = s
+		}
+	}
+}
+
 func (p *FrontpageDML) String() string {
 	d, _ := json.Marshal(p)
 	return string(d)
@@ -159,17 +224,34 @@ func (p *FrontpageDML) String() string {
 //
 // See http://diffbot.com/dev/docs/frontpage/.
 //
+// Both the XML and the JSON response formats are accepted, see
+// Options.FrontpageFormat and FrontpageDML.Parse.
+//
 func ParseFrontpage(token, url string, opt *Options) (*Frontpage, error) {
-	body, err := Diffbot("frontpage", token, url, opt)
+	return ParseFrontpageContext(context.Background(), token, url, opt)
+}
+
+// ParseFrontpageContext like ParseFrontpage function, but carries a context.
+func ParseFrontpageContext(ctx context.Context, token, url string, opt *Options) (*Frontpage, error) {
+	body, err := DiffbotContext(ctx, "frontpage", token, url, opt)
 	if err != nil {
 		return nil, err
 	}
+	if isXmlData(body) {
+		if body, err = dmlXmlToJson(body); err != nil {
+			err = &DecodeError{Method: "frontpage", Err: err}
+			opt.logDecodeError(ctx, "frontpage", err)
+			return nil, err
+		}
+	}
 	var dml FrontpageDML
-	if err := json.Unmarshal(body, &dml); err != nil {
+	if err := opt.parseJson(ctx, "frontpage", body, &dml); err != nil {
 		return nil, err
 	}
 	var page Frontpage
 	if err = page.ParseDML(&dml); err != nil {
+		err = &DecodeError{Method: "frontpage", Err: err}
+		opt.logDecodeError(ctx, "frontpage", err)
 		return nil, err
 	}
 	return &page, nil
@@ -179,7 +261,12 @@ func (p *Frontpage) ParseDML(dml *FrontpageDML) error {
 	if dml.TagName != "dml" {
 		return fmt.Errorf("diffbot: invalid FrontpageDML.")
 	}
-	*p = Frontpage{Id: dml.Id}
+	*p = Frontpage{
+		Id:       dml.Id,
+		Warnings: dml.Warnings,
+		Raw:      dml.Raw,
+		Extra:    dml.Extra,
+	}
 	for _, node := range dml.ChildNodes {
 		switch node.TagName {
 		case "info":
@@ -193,6 +280,10 @@ func (p *Frontpage) ParseDML(dml *FrontpageDML) error {
 					if len(node.ChildNodes) != 0 {
 						p.SourceURL = node.ChildNodes[0]
 					}
+				case "sourceType":
+					if len(node.ChildNodes) != 0 {
+						p.SourceType = node.ChildNodes[0]
+					}
 				case "icon":
 					if len(node.ChildNodes) != 0 {
 						p.Icon = node.ChildNodes[0]
@@ -203,18 +294,30 @@ func (p *Frontpage) ParseDML(dml *FrontpageDML) error {
 							p.NumItems = v
 						}
 					}
+				case "numSpamItems":
+					if len(node.ChildNodes) != 0 {
+						if v, err := strconv.Atoi(node.ChildNodes[0]); err == nil {
+							p.NumSpamItems = v
+						}
+					}
 				default:
-					// Unknown Fileds
+					if p.Other == nil {
+						p.Other = make(map[string]string)
+					}
+					p.Other[node.TagName] = strings.Join(node.ChildNodes, "")
 				}
 			}
 		case "item":
-			item := frontpageItemType{
-				Id:    int(node.ItemId),
-				Sp:    atof(node.ItemSp),
-				Sr:    atof(node.ItemSr),
-				Fresh: atof(node.ItemFresh),
-				Type:  node.ItemType,
-				XRoot: node.ItemXRoot,
+			item := FrontpageItem{
+				Id:           int(node.ItemId),
+				Sp:           atof(node.ItemSp),
+				Sr:           atof(node.ItemSr),
+				Fresh:        atof(node.ItemFresh),
+				Type:         node.ItemType,
+				XRoot:        node.ItemXRoot,
+				Cluster:      node.ItemCluster,
+				CommentCount: int(node.ItemCommentCount),
+				Attrs:        node.ItemAttrs,
 			}
 			for _, node := range node.ChildNodes {
 				switch node.TagName {
@@ -238,8 +341,23 @@ func (p *Frontpage) ParseDML(dml *FrontpageDML) error {
 					if len(node.ChildNodes) != 0 {
 						item.Description = node.ChildNodes[0]
 					}
+				case "img":
+					if len(node.ChildNodes) != 0 {
+						item.Img = node.ChildNodes[0]
+					}
+				case "sourceType":
+					if len(node.ChildNodes) != 0 {
+						item.SourceType = node.ChildNodes[0]
+					}
+				case "author":
+					if len(node.ChildNodes) != 0 {
+						item.Author = node.ChildNodes[0]
+					}
 				default:
-					// Unknown Fileds
+					if item.Other == nil {
+						item.Other = make(map[string]string)
+					}
+					item.Other[node.TagName] = strings.Join(node.ChildNodes, "")
 				}
 			}
 			p.Items = append(p.Items, item)
@@ -250,6 +368,11 @@ func (p *Frontpage) ParseDML(dml *FrontpageDML) error {
 	return nil
 }
 
+// PubDateTime parses the PubDate field, see ParseDate.
+func (p *FrontpageItem) PubDateTime() (time.Time, error) {
+	return ParseDate(p.PubDate)
+}
+
 func (p *Frontpage) String() string {
 	d, _ := json.Marshal(p)
 	return string(d)
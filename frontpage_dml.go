@@ -0,0 +1,146 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// ToDML converts the Frontpage to a DML, it is the inverse of ParseDML:
+// ParseDML(ToDML(p)) returns p, except the Raw and Warnings fields.
+//
+// The empty fields are omitted, except the title, numItems and the item
+// scores. The Other child nodes and the item Attrs are written by name.
+func (p *Frontpage) ToDML() *FrontpageDML {
+	dml := &FrontpageDML{
+		Id:      p.Id,
+		TagName: "dml",
+		Extra:   p.Extra,
+	}
+
+	dml.ChildNodes = append(dml.ChildNodes, frontpageDMLNodeType{})
+	node := &dml.ChildNodes[0]
+	text := func(tagName, value string, always bool) {
+		if value == "" && !always {
+			return
+		}
+		node.ChildNodes = append(node.ChildNodes, frontpageDMLTextNodeType{
+			TagName:    tagName,
+			ChildNodes: []string{value},
+		})
+	}
+
+	node.TagName = "info"
+	text("title", p.Title, true)
+	text("sourceType", p.SourceType, false)
+	text("sourceURL", p.SourceURL, false)
+	text("icon", p.Icon, false)
+	text("numItems", strconv.Itoa(p.NumItems), true)
+	if p.NumSpamItems != 0 {
+		text("numSpamItems", strconv.Itoa(p.NumSpamItems), true)
+	}
+	for _, name := range sortedKeys(p.Other) {
+		text(name, p.Other[name], true)
+	}
+
+	for _, item := range p.Items {
+		dml.ChildNodes = append(dml.ChildNodes, frontpageDMLNodeType{})
+		node = &dml.ChildNodes[len(dml.ChildNodes)-1]
+		node.TagName = "item"
+		node.ItemId = int64(item.Id)
+		node.ItemSp = formatScore(item.Sp)
+		node.ItemFresh = formatScore(item.Fresh)
+		node.ItemSr = formatScore(item.Sr)
+		node.ItemType = item.Type
+		node.ItemCluster = item.Cluster
+		node.ItemXRoot = item.XRoot
+		node.ItemCommentCount = int64(item.CommentCount)
+		node.ItemAttrs = item.Attrs
+
+		text("title", item.Title, false)
+		text("link", item.Link, false)
+		text("pubDate", item.PubDate, false)
+		text("textSummary", item.TextSummary, false)
+		text("description", item.Description, false)
+		text("img", item.Img, false)
+		text("sourceType", item.SourceType, false)
+		text("author", item.Author, false)
+		for _, name := range sortedKeys(item.Other) {
+			text(name, item.Other[name], true)
+		}
+	}
+	return dml
+}
+
+// formatScore formats the item score like Diffbot, e.g. "0.000".
+// More digits are used if needed.
+func formatScore(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 3, 64)
+	if atof(s) != v {
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return s
+}
+
+// MarshalJSON implements json.Marshaler, it encodes the DML as the JSON
+// rendering. Unlike the default encoding, the ItemAttrs are written as
+// the fields of the nodes, and the Extra fields are kept.
+func (p *FrontpageDML) MarshalJSON() ([]byte, error) {
+	root := make(map[string]interface{})
+	for key, value := range p.Extra {
+		root[key] = value
+	}
+	root["id"] = strconv.FormatInt(p.Id, 10)
+	root["tagName"] = p.TagName
+	nodes := make([]interface{}, 0, len(p.ChildNodes))
+	for i, node := range p.ChildNodes {
+		m := map[string]interface{}{
+			"tagName": node.TagName,
+		}
+		for _, attr := range p.nodeAttrs(i) {
+			m[attr.Name.Local] = attr.Value
+		}
+		children := make([]interface{}, 0, len(node.ChildNodes))
+		for _, child := range node.ChildNodes {
+			text := child.ChildNodes
+			if text == nil {
+				text = []string{}
+			}
+			children = append(children, map[string]interface{}{
+				"tagName":    child.TagName,
+				"childNodes": text,
+			})
+		}
+		m["childNodes"] = children
+		nodes = append(nodes, m)
+	}
+	root["childNodes"] = nodes
+	return json.Marshal(root)
+}
+
+// EncodeJson writes the DML as indented JSON, see MarshalJSON.
+func (p *FrontpageDML) EncodeJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// EncodeXml writes the DML as indented XML, see MarshalXML.
+func (p *FrontpageDML) EncodeXml(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(p); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,97 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testParseFrontpage(t *testing.T, data string) *Frontpage {
+	var dml FrontpageDML
+	if err := dml.Parse([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	var page Frontpage
+	if err := page.ParseDML(&dml); err != nil {
+		t.Fatal(err)
+	}
+	page.Raw = nil
+	return &page
+}
+
+func TestFrontpage_toDML(t *testing.T) {
+	for _, data := range []string{testJsonDataFrontpage, testJsonDataFrontpageOther, testXmlDataFrontpage} {
+		page := testParseFrontpage(t, data)
+
+		var page2 Frontpage
+		if err := page2.ParseDML(page.ToDML()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(page, &page2) {
+			t.Fatalf("not equal, expect = \n%v, got = \n%v", page, &page2)
+		}
+	}
+}
+
+func TestFrontpageDML_encode(t *testing.T) {
+	for _, data := range []string{testJsonDataFrontpage, testJsonDataFrontpageOther, testXmlDataFrontpage} {
+		page := testParseFrontpage(t, data)
+		dml := page.ToDML()
+
+		var jsonBuf, xmlBuf bytes.Buffer
+		if err := dml.EncodeJson(&jsonBuf); err != nil {
+			t.Fatal(err)
+		}
+		if err := dml.EncodeXml(&xmlBuf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(xmlBuf.String(), "<?xml") {
+			t.Fatalf("invalid xml header: %.40s", xmlBuf.String())
+		}
+		if !json.Valid(jsonBuf.Bytes()) {
+			t.Fatalf("invalid json: %s", jsonBuf.String())
+		}
+
+		for _, encoded := range []string{jsonBuf.String(), xmlBuf.String()} {
+			page2 := testParseFrontpage(t, encoded)
+			if !reflect.DeepEqual(page, page2) {
+				t.Fatalf("not equal, expect = \n%v, got = \n%v", page, page2)
+			}
+		}
+	}
+}
+
+func TestFrontpageDML_marshalJSON(t *testing.T) {
+	var dml FrontpageDML
+	if err := dml.ParseJson([]byte(testJsonDataFrontpageOther)); err != nil {
+		t.Fatal(err)
+	}
+	d, err := json.Marshal(&dml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dml2 FrontpageDML
+	if err := dml2.ParseJson(d); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := dml2.ChildNodes[1].ItemAttrs, map[string]string{"lang": "en", "rank": "3"}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if !reflect.DeepEqual(dml.ChildNodes, dml2.ChildNodes) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", dml.ChildNodes, dml2.ChildNodes)
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	for v, s := range map[float64]string{0: "0.000", 0.101: "0.101", 4: "4.000", 0.12345: "0.12345"} {
+		if a, b := formatScore(v), s; a != b {
+			t.Fatalf("expect = %v, got = %v", b, a)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFollowConcurrency = 4
+	defaultFollowMaxRetries  = 3
+	defaultFollowRetryDelay  = time.Second
+)
+
+// FrontpageArticle pairs a frontpage item with its extracted article,
+// see FrontpageFollower.
+type FrontpageArticle struct {
+	Item    *FrontpageItem
+	Article *Article // Nil if Err is not nil
+	Err     error
+}
+
+// FrontpageFollower calls ParseFrontpage, and then ParseArticle on the
+// link of each item with bounded concurrency.
+//
+// Only the items of Types ("STORY" by default) are followed, and the items
+// with Sp above MaxSp or Fresh below MinFresh are skipped, if they are set.
+//
+// If an article is rate limited (ErrRateLimited), all the calls are paused
+// for the Retry-After delay of the response (one second if missing), and
+// the article is retried up to MaxRetries times.
+type FrontpageFollower struct {
+	Token   string
+	Options *Options // Options of the frontpage and article calls.
+
+	Types       []string // Item types to follow, default is "STORY", "*" is all.
+	MaxSp       float64  // Skip the items with a higher spam score, 0 is disabled.
+	MinFresh    float64  // Skip the items with a lower freshness, 0 is disabled.
+	Concurrency int      // Max number of article calls at a time, default is 4.
+	MaxRetries  int      // Retries of a rate limited article, default is 3, -1 is none.
+}
+
+func (p *FrontpageFollower) filter() *FrontpageItemFilter {
+	filter := &FrontpageItemFilter{Types: p.Types, MaxSp: p.MaxSp, MinFresh: p.MinFresh}
+	if len(filter.Types) == 0 {
+		filter.Types = []string{"STORY"}
+	}
+	return filter
+}
+
+func (p *FrontpageFollower) concurrency() int {
+	if p.Concurrency > 0 {
+		return p.Concurrency
+	}
+	return defaultFollowConcurrency
+}
+
+func (p *FrontpageFollower) maxRetries() int {
+	switch {
+	case p.MaxRetries == 0:
+		return defaultFollowMaxRetries
+	case p.MaxRetries < 0:
+		return 0
+	}
+	return p.MaxRetries
+}
+
+// Follow parses the frontpage of url, and the articles of its items.
+//
+// The error is only returned if the frontpage itself fails, the failure
+// of an article is kept in FrontpageArticle.Err.
+func (p *FrontpageFollower) Follow(ctx context.Context, url string) (*Frontpage, []FrontpageArticle, error) {
+	page, err := ParseFrontpageContext(ctx, p.Token, url, p.Options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, p.ParseArticles(ctx, page), nil
+}
+
+// ParseArticles calls ParseArticleContext on the link of each followed
+// item of page, the result keeps the order of the items. If ctx is done,
+// the remaining items fail with the ctx error.
+func (p *FrontpageFollower) ParseArticles(ctx context.Context, page *Frontpage) []FrontpageArticle {
+	var result []FrontpageArticle
+	for _, item := range page.Filter(p.filter()) {
+		result = append(result, FrontpageArticle{Item: item})
+	}
+
+	gate := new(followGate)
+	sem := make(chan struct{}, p.concurrency())
+	maxRetries := p.maxRetries()
+
+	var wg sync.WaitGroup
+	for i := range result {
+		wg.Add(1)
+		go func(r *FrontpageArticle) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				return
+			}
+			for retry := 0; ; retry++ {
+				if r.Err = gate.wait(ctx); r.Err != nil {
+					return
+				}
+				r.Article, r.Err = ParseArticleContext(ctx, p.Token, r.Item.Link, p.Options)
+				if !errors.Is(r.Err, ErrRateLimited) || retry >= maxRetries {
+					return
+				}
+				delay := followRetryDelay(r.Err)
+				url := makeRequestUrl(DefaultServer, "article", p.Token, r.Item.Link, p.Options)
+				p.Options.logRetry(ctx, "article", url, retry+1, delay, r.Err)
+				gate.pause(delay)
+			}
+		}(&result[i])
+	}
+	wg.Wait()
+	return result
+}
+
+// followRetryDelay returns the Retry-After delay of the rate limited error.
+func followRetryDelay(err error) time.Duration {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		if d, ok := apiErr.RetryAfter(); ok {
+			return d
+		}
+	}
+	return defaultFollowRetryDelay
+}
+
+// followGate pauses the calls of all workers after a rate limited call.
+type followGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (p *followGate) pause(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(d); until.After(p.until) {
+		p.until = until
+	}
+}
+
+func (p *followGate) wait(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		d := time.Until(p.until)
+		p.mu.Unlock()
+		if d <= 0 {
+			return ctx.Err()
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testFollowFrontpage = &Frontpage{
+	Title:    "Follow",
+	NumItems: 7,
+	Items: []FrontpageItem{
+		{Id: 1, Type: "STORY", Link: "http://a.com/1", Fresh: 1},
+		{Id: 2, Type: "LINK", Link: "http://a.com/2", Fresh: 1},
+		{Id: 3, Type: "STORY", Link: "http://a.com/3", Fresh: 1, Sp: 0.9},
+		{Id: 4, Type: "STORY", Link: "http://a.com/4", Fresh: 0.1},
+		{Id: 5, Type: "STORY", Link: "http://a.com/missing", Fresh: 1},
+		{Id: 6, Type: "STORY", Link: "http://a.com/limited", Fresh: 1},
+		{Id: 7, Type: "STORY", Fresh: 1},
+	},
+}
+
+// testFollowServer serves the testFollowFrontpage and its articles. The
+// "missing" article is not found, and the "limited" article is rate
+// limited on the first call.
+type testFollowServer struct {
+	mu        sync.Mutex
+	calls     map[string]int
+	active    int
+	maxActive int
+}
+
+func (p *testFollowServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/frontpage" {
+		testFollowFrontpage.ToDML().EncodeJson(w)
+		return
+	}
+	link := r.URL.Query().Get("url")
+
+	p.mu.Lock()
+	p.calls[link]++
+	calls := p.calls[link]
+	if p.active++; p.active > p.maxActive {
+		p.maxActive = p.active
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	switch {
+	case strings.HasSuffix(link, "/missing"):
+		http.Error(w, `{"error":"Could not download page (404)","errorCode":404}`, 404)
+	case strings.HasSuffix(link, "/limited") && calls == 1:
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, `{"error":"Too many calls.","errorCode":429}`, 429)
+	default:
+		fmt.Fprintf(w, `{"type":"article","url":%q,"title":"Title of %s"}`, link, link)
+	}
+}
+
+func TestFrontpageFollower(t *testing.T) {
+	fs := &testFollowServer{calls: make(map[string]int)}
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	follower := &FrontpageFollower{
+		Token: "token",
+		Options: &Options{
+			Client: testRedirectDoer(ts.URL),
+			Logger: slog.New(slog.NewTextHandler(&buf, nil)),
+		},
+		MaxSp:       0.5,
+		MinFresh:    0.5,
+		Concurrency: 2,
+	}
+	page, articles, err := follower.Follow(context.Background(), "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := page.Title, "Follow"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	var ids []int
+	for _, r := range articles {
+		ids = append(ids, r.Item.Id)
+	}
+	if a, b := ids, []int{1, 5, 6}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if r := articles[0]; r.Err != nil || r.Article.Title != "Title of http://a.com/1" {
+		t.Fatalf("unexpected result: %v, %v", r.Article, r.Err)
+	}
+	if r := articles[1]; r.Article != nil || !errors.Is(r.Err, ErrNotFound) {
+		t.Fatalf("unexpected result: %v, %v", r.Article, r.Err)
+	}
+	if r := articles[2]; r.Err != nil || r.Article.Title != "Title of http://a.com/limited" {
+		t.Fatalf("unexpected result: %v, %v", r.Article, r.Err)
+	}
+	if a, b := fs.calls["http://a.com/limited"], 2; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if fs.maxActive > 2 {
+		t.Fatalf("too many concurrent calls: %d", fs.maxActive)
+	}
+	if s := `msg="diffbot retry" method=article url="` + DefaultServer + `/article?token=REDACTED&url=http%3A%2F%2Fa.com%2Flimited`; !strings.Contains(buf.String(), s) {
+		t.Fatalf("missing %q in:\n%s", s, buf.String())
+	}
+}
+
+func TestFrontpageFollower_parseArticles(t *testing.T) {
+	fs := &testFollowServer{calls: make(map[string]int)}
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	// All types, without retries.
+	follower := &FrontpageFollower{
+		Token:      "token",
+		Options:    &Options{Client: testRedirectDoer(ts.URL)},
+		Types:      []string{"*"},
+		MaxRetries: -1,
+	}
+	articles := follower.ParseArticles(context.Background(), testFollowFrontpage)
+	if a, b := len(articles), 6; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if r := articles[5]; !errors.Is(r.Err, ErrRateLimited) {
+		t.Fatalf("unexpected result: %v, %v", r.Article, r.Err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, r := range follower.ParseArticles(ctx, testFollowFrontpage) {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Fatalf("unexpected result: %v, %v", r.Article, r.Err)
+		}
+	}
+}
+
+func TestFollowGate(t *testing.T) {
+	gate := new(followGate)
+	gate.pause(50 * time.Millisecond)
+	gate.pause(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := gate.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect = %v, got = %v", context.DeadlineExceeded, err)
+	}
+	if err := gate.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(gate.until); d > 0 {
+		t.Fatalf("gate is still paused: %v", d)
+	}
+}
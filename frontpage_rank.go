@@ -0,0 +1,175 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"sort"
+	"strings"
+)
+
+// FrontpageSection is a group of items in the same part of the page,
+// e.g. the top stories or a sidebar, see Frontpage.Sections.
+type FrontpageSection struct {
+	Path  string           // The XPath of the section
+	Items []*FrontpageItem // In the page order
+}
+
+// Score returns the mean score of the section items, or 0 if empty.
+func (p *FrontpageSection) Score(score FrontpageScoreFunc) float64 {
+	if len(p.Items) == 0 {
+		return 0
+	}
+	if score == nil {
+		score = DefaultFrontpageScore
+	}
+	var sum float64
+	for _, item := range p.Items {
+		sum += score(item)
+	}
+	return sum / float64(len(p.Items))
+}
+
+// Sections groups the items into sections, in the order of the first item
+// of each section.
+//
+// If depth is zero, the items are grouped by the Cluster, or by the parent
+// of the XRoot if the cluster is missing. Otherwise the items are grouped
+// by the first depth steps of the XRoot, e.g. "/HTML[1]/BODY[1]/DIV[4]"
+// for depth 3. If the XRoot lists several elements, their common parent
+// is used.
+func (p *Frontpage) Sections(depth int) []FrontpageSection {
+	var sections []FrontpageSection
+	index := make(map[string]int)
+	for i := range p.Items {
+		item := &p.Items[i]
+		path := frontpageItemPath(item, depth)
+		n, ok := index[path]
+		if !ok {
+			n = len(sections)
+			index[path] = n
+			sections = append(sections, FrontpageSection{Path: path})
+		}
+		sections[n].Items = append(sections[n].Items, item)
+	}
+	return sections
+}
+
+// frontpageItemPath returns the section path of the item, see Sections.
+func frontpageItemPath(item *FrontpageItem, depth int) string {
+	if depth == 0 && item.Cluster != "" {
+		return item.Cluster
+	}
+	steps := xrootSteps(item.XRoot)
+	if depth == 0 {
+		if len(steps) != 0 {
+			steps = steps[:len(steps)-1]
+		}
+	} else if depth < len(steps) {
+		steps = steps[:depth]
+	}
+	if len(steps) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(steps, "/")
+}
+
+// xrootSteps returns the XPath steps of the xroot. If the xroot lists
+// several comma separated elements, the steps of their common parent
+// are returned.
+func xrootSteps(xroot string) []string {
+	var steps []string
+	for i, path := range strings.Split(xroot, ",") {
+		s := strings.Split(strings.Trim(strings.TrimSpace(path), "/"), "/")
+		if i == 0 {
+			steps = s
+			continue
+		}
+		n := 0
+		for n < len(steps) && n < len(s) && steps[n] == s[n] {
+			n++
+		}
+		steps = steps[:n]
+	}
+	if len(steps) == 1 && steps[0] == "" {
+		return nil
+	}
+	return steps
+}
+
+// FrontpageScoreFunc returns the score of an item, the higher is better.
+type FrontpageScoreFunc func(item *FrontpageItem) float64
+
+// DefaultFrontpageScore scores an item by the static rank, weighted by the
+// freshness and the probability of not being spam:
+//
+//	Sr * Fresh * (1 - Sp)
+func DefaultFrontpageScore(item *FrontpageItem) float64 {
+	return item.Sr * item.Fresh * (1 - item.Sp)
+}
+
+// Ranked returns the items sorted by the score, the higher first. The
+// items of equal scores keep the page order. DefaultFrontpageScore is
+// used if score is nil.
+func (p *Frontpage) Ranked(score FrontpageScoreFunc) []*FrontpageItem {
+	if score == nil {
+		score = DefaultFrontpageScore
+	}
+	items := make([]*FrontpageItem, len(p.Items))
+	scores := make(map[*FrontpageItem]float64, len(p.Items))
+	for i := range p.Items {
+		items[i] = &p.Items[i]
+		scores[items[i]] = score(items[i])
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return scores[items[i]] > scores[items[j]]
+	})
+	return items
+}
+
+// FrontpageItemFilter selects the frontpage items, the zero fields are
+// ignored, see Frontpage.Filter.
+type FrontpageItemFilter struct {
+	Types      []string // Item types, e.g. "STORY", "*" is all
+	MaxSp      float64  // Skip the items with a higher spam score
+	MinSr      float64  // Skip the items with a lower static rank
+	MinFresh   float64  // Skip the items with a lower freshness
+	KeepNoLink bool     // Keep the items without a link
+}
+
+// Match reports whether the item is selected by the filter.
+func (p *FrontpageItemFilter) Match(item *FrontpageItem) bool {
+	if item.Link == "" && !p.KeepNoLink {
+		return false
+	}
+	if p.MaxSp > 0 && item.Sp > p.MaxSp {
+		return false
+	}
+	if p.MinSr > 0 && item.Sr < p.MinSr {
+		return false
+	}
+	if p.MinFresh > 0 && item.Fresh < p.MinFresh {
+		return false
+	}
+	if len(p.Types) == 0 {
+		return true
+	}
+	for _, typ := range p.Types {
+		if typ == "*" || typ == item.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the items selected by the filter, in the page order.
+func (p *Frontpage) Filter(filter *FrontpageItemFilter) []*FrontpageItem {
+	var items []*FrontpageItem
+	for i := range p.Items {
+		if filter.Match(&p.Items[i]) {
+			items = append(items, &p.Items[i])
+		}
+	}
+	return items
+}
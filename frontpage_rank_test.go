@@ -0,0 +1,139 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrontpage_sections(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+
+	type section struct {
+		Path string
+		Size int
+	}
+	sections := func(depth int) []section {
+		var result []section
+		for _, s := range page.Sections(depth) {
+			result = append(result, section{s.Path, len(s.Items)})
+		}
+		return result
+	}
+	if a, b := sections(0), []section{
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]", 26},
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[9]/DIV[2]/DIV[6]/DIV[3]", 14},
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[9]/DIV[1]/DIV[2]/DIV[2]", 1},
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[9]/DIV[1]/DIV[2]", 10},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := sections(5), []section{
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]", 51},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := sections(8), []section{
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]", 26},
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[9]/DIV[2]/DIV[6]", 14},
+		{"/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[9]/DIV[1]/DIV[2]", 11},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	s := page.Sections(0)[1]
+	if a, b := s.Items[0], &page.Items[26]; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := s.Score(nil), 4.0; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := (&FrontpageSection{}).Score(nil), 0.0; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFrontpageItemPath(t *testing.T) {
+	for _, v := range []struct {
+		Item  FrontpageItem
+		Depth int
+		Path  string
+	}{
+		{FrontpageItem{Cluster: "/HTML[1]/BODY[1]", XRoot: "/HTML[1]/BODY[1]/DIV[1]/DIV[2]"}, 0, "/HTML[1]/BODY[1]"},
+		{FrontpageItem{XRoot: "/HTML[1]/BODY[1]/DIV[1]/DIV[2]"}, 0, "/HTML[1]/BODY[1]/DIV[1]"},
+		{FrontpageItem{XRoot: "/HTML[1]/BODY[1]/DIV[1]/DIV[2]"}, 3, "/HTML[1]/BODY[1]/DIV[1]"},
+		{FrontpageItem{XRoot: "/HTML[1]/BODY[1]/DIV[1]/DIV[2]"}, 9, "/HTML[1]/BODY[1]/DIV[1]/DIV[2]"},
+		{FrontpageItem{XRoot: "/HTML[1]/BODY[1]/DIV[1]/DIV[2],/HTML[1]/BODY[1]/DIV[1]/DIV[3]"}, 0, "/HTML[1]/BODY[1]"},
+		{FrontpageItem{XRoot: "/HTML[1]/BODY[1]/DIV[1]/DIV[2],/HTML[1]/BODY[1]/DIV[1]/DIV[3]"}, 9, "/HTML[1]/BODY[1]/DIV[1]"},
+		{FrontpageItem{}, 0, ""},
+		{FrontpageItem{}, 3, ""},
+	} {
+		if a, b := frontpageItemPath(&v.Item, v.Depth), v.Path; a != b {
+			t.Fatalf("%v: expect = %v, got = %v", v.Item.XRoot, b, a)
+		}
+	}
+}
+
+func TestFrontpage_ranked(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+
+	var ids []int
+	for _, item := range page.Ranked(nil)[:6] {
+		ids = append(ids, item.Id)
+	}
+	if a, b := ids, []int{-1534913246, -600635503, -1604519136, -811364529, 2050734786, 1264289762}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	items := page.Ranked(nil)
+	if a, b := items[len(items)-1].Id, -91871119; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// Rank the links last, then by the page order.
+	items = page.Ranked(func(item *FrontpageItem) float64 {
+		if item.Type == "LINK" {
+			return 0
+		}
+		return 1
+	})
+	if a, b := items[0], &page.Items[0]; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	for _, item := range items[len(items)-6:] {
+		if a, b := item.Type, "LINK"; a != b {
+			t.Fatalf("expect = %v, got = %v", b, a)
+		}
+	}
+}
+
+func TestFrontpage_filter(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+
+	if a, b := len(page.Filter(&FrontpageItemFilter{})), 51; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := len(page.Filter(&FrontpageItemFilter{Types: []string{"LINK"}})), 6; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := len(page.Filter(&FrontpageItemFilter{Types: []string{"STORY"}, MaxSp: 0.05})), 40; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := len(page.Filter(&FrontpageItemFilter{MinSr: 4.5})), 6; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := len(page.Filter(&FrontpageItemFilter{MinFresh: 1.5})), 0; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	filter := &FrontpageItemFilter{}
+	if filter.Match(&FrontpageItem{Type: "STORY"}) {
+		t.Fatal("expect the item without link is skipped")
+	}
+	filter.KeepNoLink = true
+	if !filter.Match(&FrontpageItem{Type: "STORY"}) {
+		t.Fatal("expect the item without link is kept")
+	}
+}
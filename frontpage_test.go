@@ -5,13 +5,14 @@
 package diffbot
 
 import (
+	"encoding/xml"
 	"reflect"
 	"testing"
 )
 
 func TestFrontpage_type(t *testing.T) {
 	var a Frontpage
-	a.Items = append(a.Items, frontpageItemType{})
+	a.Items = append(a.Items, FrontpageItem{})
 	_ = a
 }
 
@@ -25,6 +26,11 @@ func TestFrontpage_parseDML(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if a, b := testJsonDataFrontpage, string(page.Raw); a != b {
+		t.Fatalf("not equal, expect = \n%q, got = \n%q", a, b)
+	}
+	page.Raw = nil
+
 	items := page.Items
 	page.Items = nil
 
@@ -34,39 +40,125 @@ func TestFrontpage_parseDML(t *testing.T) {
 	for i := 0; i < len(items) && i < len(testGoldenFrontpageItems); i++ {
 		items[i].Description = "" // too large, ingore
 		items[i].TextSummary = "" // too large, ingore
-		if !reflect.DeepEqual(testGoldenFrontpageItems[i], frontpageItemType(items[i])) {
+		if !reflect.DeepEqual(testGoldenFrontpageItems[i], FrontpageItem(items[i])) {
 			t.Fatalf("not equal, expect = \n%q, got = \n%q", testGoldenFrontpageItems[i], items[i])
 		}
 	}
 }
 
+func TestFrontpage_parseDMLOther(t *testing.T) {
+	var dml FrontpageDML
+	if err := dml.ParseJson([]byte(testJsonDataFrontpageOther)); err != nil {
+		t.Fatal(err)
+	}
+	var page Frontpage
+	if err := page.ParseDML(&dml); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := page.Other, map[string]string{"lang": "en"}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := page.Items[0], testGoldenFrontpageItemOther; !reflect.DeepEqual(a, b) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", b, a)
+	}
+
+	// The other attributes and children are kept by XML.
+	data, err := xml.Marshal(&dml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dml2 FrontpageDML
+	if err := dml2.ParseXml(data); err != nil {
+		t.Fatal(err)
+	}
+	var page2 Frontpage
+	if err := page2.ParseDML(&dml2); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := page2.Items[0], testGoldenFrontpageItemOther; !reflect.DeepEqual(a, b) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", b, a)
+	}
+}
+
+var testGoldenFrontpageItemOther = FrontpageItem{
+	Id:           42,
+	Title:        "Title",
+	Link:         "http://example.com/a.html",
+	Type:         "STORY",
+	Img:          "http://example.com/a.jpg",
+	SourceType:   "rss",
+	Author:       "John Davi",
+	CommentCount: 7,
+	Cluster:      "/HTML[1]/BODY[1]",
+	XRoot:        "/HTML[1]/BODY[1]/DIV[1]",
+	Sp:           0.5,
+	Attrs:        map[string]string{"lang": "en", "rank": "3"},
+	Other:        map[string]string{"category": "News"},
+}
+
+const testJsonDataFrontpageOther = `{
+  "tagName": "dml",
+  "childNodes": [
+    {
+      "tagName": "info",
+      "childNodes": [
+        {"tagName": "title", "childNodes": ["Page"]},
+        {"tagName": "lang", "childNodes": ["en"]}
+      ]
+    },
+    {
+      "tagName": "item",
+      "id": "42",
+      "sp": "0.500",
+      "type": "STORY",
+      "cluster": "/HTML[1]/BODY[1]",
+      "xroot": "/HTML[1]/BODY[1]/DIV[1]",
+      "commentCount": "7",
+      "lang": "en",
+      "rank": "3",
+      "childNodes": [
+        {"tagName": "title", "childNodes": ["Title"]},
+        {"tagName": "link", "childNodes": ["http://example.com/a.html"]},
+        {"tagName": "img", "childNodes": ["http://example.com/a.jpg"]},
+        {"tagName": "sourceType", "childNodes": ["rss"]},
+        {"tagName": "author", "childNodes": ["John Davi"]},
+        {"tagName": "category", "childNodes": ["News"]}
+      ]
+    }
+  ]
+}`
+
 var testGoldenFrontpage = Frontpage{
-	Id:        0,
-	Title:     "Breaking News and Opinion on The Huffington Post",
-	SourceURL: "http://www.huffingtonpost.com",
-	Icon:      "http://www.huffingtonpost.com:80/favicon.ico",
-	NumItems:  51,
+	Id:         0,
+	Title:      "Breaking News and Opinion on The Huffington Post",
+	SourceURL:  "http://www.huffingtonpost.com",
+	SourceType: "html",
+	Icon:       "http://www.huffingtonpost.com:80/favicon.ico",
+	NumItems:   51,
 }
-var testGoldenFrontpageItems = []frontpageItemType{
+var testGoldenFrontpageItems = []FrontpageItem{
 	{
-		Id:          180194704,
-		Title:       "The Austerity Trap and the Jobs Deficit",
-		Description: "", // too large, ingore
-		XRoot:       "/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]/DIV[1]",
-		PubDate:     "Thu, 17 May 2012 20:06:26 GMT",
-		Link:        "http://www.huffingtonpost.com:80/robert-l-borosage/the-austerity-trap-and-th_b_1524662.html",
-		Type:        "STORY",
-		Img:         "",
-		TextSummary: "", // too large, ingore
-		Sp:          0.000,
-		Sr:          4.000,
-		Fresh:       1.000,
+		Id:           180194704,
+		Title:        "The Austerity Trap and the Jobs Deficit",
+		Description:  "", // too large, ingore
+		XRoot:        "/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]/DIV[1]",
+		Cluster:      "/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]",
+		PubDate:      "Thu, 17 May 2012 20:06:26 GMT",
+		Link:         "http://www.huffingtonpost.com:80/robert-l-borosage/the-austerity-trap-and-th_b_1524662.html",
+		Type:         "STORY",
+		Img:          "",
+		TextSummary:  "", // too large, ingore
+		Sp:           0.000,
+		CommentCount: 34,
+		Sr:           4.000,
+		Fresh:        1.000,
 	},
 	{
 		Id:          -91871119,
 		Title:       "Bringing Water And Sanitation To Ethiopian Children",
 		Description: "", // too large, ingore
 		XRoot:       "/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]/DIV[2]",
+		Cluster:     "/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]",
 		PubDate:     "Thu, 17 May 2012 20:06:26 GMT",
 		Link:        "http://at.atwola.com:80/adlink/3.0/5113.1/2298499/1/16/AdId=2520773;BnId=1;link=http://www.huffingtonpost.com/conrad-person/caring-for-our-future-bri_b_1521005.htmlhttp://www.huffingtonpost.com/conrad-person/caring-for-our-future-bri_b_1521005.html",
 		Type:        "STORY",
@@ -0,0 +1,392 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FrontpageEventType is the type of a FrontpageEvent.
+type FrontpageEventType int
+
+const (
+	FrontpageItemAdded   FrontpageEventType = iota + 1 // A new item
+	FrontpageItemRemoved                               // An item of the previous poll is gone
+	FrontpageItemChanged                               // An item is updated, e.g. the title
+	FrontpageError                                     // The poll of a frontpage failed
+)
+
+func (p FrontpageEventType) String() string {
+	switch p {
+	case FrontpageItemAdded:
+		return "added"
+	case FrontpageItemRemoved:
+		return "removed"
+	case FrontpageItemChanged:
+		return "changed"
+	case FrontpageError:
+		return "error"
+	}
+	return "FrontpageEventType(" + strconv.Itoa(int(p)) + ")"
+}
+
+// FrontpageEvent is a change of a watched frontpage, see FrontpageWatcher.
+type FrontpageEvent struct {
+	Type FrontpageEventType
+	Url  string         // The frontpage URL
+	Item *FrontpageItem // The added or changed item, or the removed item
+	Old  *FrontpageItem // The previous item, for FrontpageItemChanged
+	Err  error          // For FrontpageError
+	Time time.Time      // The time of the poll
+}
+
+// FrontpageStore keeps the last snapshot of the watched frontpages.
+type FrontpageStore interface {
+	// Load returns the snapshot of the url, or nil if there is none.
+	Load(ctx context.Context, url string) (*Frontpage, error)
+	Save(ctx context.Context, url string, page *Frontpage) error
+}
+
+// MemoryFrontpageStore is a FrontpageStore in memory.
+type MemoryFrontpageStore struct {
+	mu    sync.Mutex
+	pages map[string]*Frontpage
+}
+
+// NewMemoryFrontpageStore returns an empty MemoryFrontpageStore.
+func NewMemoryFrontpageStore() *MemoryFrontpageStore {
+	return &MemoryFrontpageStore{pages: make(map[string]*Frontpage)}
+}
+
+func (p *MemoryFrontpageStore) Load(ctx context.Context, url string) (*Frontpage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pages[url], nil
+}
+
+func (p *MemoryFrontpageStore) Save(ctx context.Context, url string, page *Frontpage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pages[url] = page
+	return nil
+}
+
+// FileFrontpageStore is a FrontpageStore which keeps each snapshot as
+// a JSON file in the Dir directory.
+type FileFrontpageStore struct {
+	Dir string
+}
+
+func (p *FileFrontpageStore) filename(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(p.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (p *FileFrontpageStore) Load(ctx context.Context, url string) (*Frontpage, error) {
+	data, err := os.ReadFile(p.filename(url))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var page Frontpage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (p *FileFrontpageStore) Save(ctx context.Context, url string, page *Frontpage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return err
+	}
+	// Write and rename, so a crash does not leave a partial snapshot.
+	name := p.filename(url)
+	if err := os.WriteFile(name+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+const (
+	// DefaultFrontpageWatchInterval is the default poll interval of FrontpageWatcher.
+	DefaultFrontpageWatchInterval = 5 * time.Minute
+
+	// MinFrontpageWatchInterval is the min poll interval of FrontpageWatcher,
+	// after the jitter.
+	MinFrontpageWatchInterval = time.Second
+
+	// maxFrontpageWatchJitter is the max Jitter of FrontpageWatcher.
+	maxFrontpageWatchJitter = 0.9
+)
+
+// FrontpageWatcher polls frontpages with ParseFrontpage, and reports the
+// added, removed and changed items between the polls.
+//
+// The items of two polls are matched by the item id, then by the link,
+// and then by the xroot.
+type FrontpageWatcher struct {
+	Token   string
+	Urls    []string
+	Options *Options
+
+	// Store keeps the snapshots between the polls, a MemoryFrontpageStore
+	// is used if nil.
+	Store FrontpageStore
+
+	// Interval is the time between the polls, DefaultFrontpageWatchInterval
+	// if zero. Each interval is randomized by ±Jitter (e.g. 0.1 is ±10%),
+	// the Jitter is at most 0.9, and the interval is at least
+	// MinFrontpageWatchInterval.
+	Interval time.Duration
+	Jitter   float64
+
+	// SkipInitial suppresses the added events of the first poll of a
+	// frontpage without snapshot.
+	SkipInitial bool
+
+	// Changed reports whether the matched item is changed, the title,
+	// link, type, image, summary or description are compared if nil.
+	Changed func(old, cur *FrontpageItem) bool
+
+	once sync.Once
+}
+
+func (p *FrontpageWatcher) store() FrontpageStore {
+	p.once.Do(func() {
+		if p.Store == nil {
+			p.Store = NewMemoryFrontpageStore()
+		}
+	})
+	return p.Store
+}
+
+func (p *FrontpageWatcher) interval() time.Duration {
+	d := p.Interval
+	if d <= 0 {
+		d = DefaultFrontpageWatchInterval
+	}
+	if jitter := p.Jitter; jitter > 0 {
+		if jitter > maxFrontpageWatchJitter {
+			jitter = maxFrontpageWatchJitter
+		}
+		d += time.Duration((rand.Float64()*2 - 1) * jitter * float64(d))
+	}
+	if d < MinFrontpageWatchInterval {
+		d = MinFrontpageWatchInterval
+	}
+	return d
+}
+
+// Watch polls the frontpages until ctx is done, the first poll starts
+// immediately. The returned channel is closed when ctx is done.
+//
+// The snapshot of a frontpage is saved after all its events are received,
+// so the events which are not received are reported again by the next
+// watch with the same Store.
+func (p *FrontpageWatcher) Watch(ctx context.Context) <-chan FrontpageEvent {
+	events := make(chan FrontpageEvent)
+	send := func(list []FrontpageEvent) bool {
+		for _, event := range list {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+	go func() {
+		defer close(events)
+		for {
+			for _, url := range p.Urls {
+				if ctx.Err() != nil {
+					return
+				}
+				list, page := p.poll(ctx, url)
+				if !send(list) {
+					return
+				}
+				if !send(p.save(ctx, url, page)) {
+					return
+				}
+			}
+			timer := time.NewTimer(p.interval())
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// Poll polls each frontpage once, and returns the events since the
+// previous poll. The failures are reported as FrontpageError events.
+func (p *FrontpageWatcher) Poll(ctx context.Context) []FrontpageEvent {
+	var events []FrontpageEvent
+	for _, url := range p.Urls {
+		if ctx.Err() != nil {
+			break
+		}
+		list, page := p.poll(ctx, url)
+		events = append(events, list...)
+		events = append(events, p.save(ctx, url, page)...)
+	}
+	return events
+}
+
+// poll returns the events of the url, and the page to save after the
+// events are delivered, which is nil if the poll failed.
+func (p *FrontpageWatcher) poll(ctx context.Context, url string) ([]FrontpageEvent, *Frontpage) {
+	now := time.Now()
+	old, err := p.store().Load(ctx, url)
+	if err != nil {
+		return []FrontpageEvent{frontpageErrorEvent(url, err, now)}, nil
+	}
+	page, err := ParseFrontpageContext(ctx, p.Token, url, p.Options)
+	if err != nil {
+		return []FrontpageEvent{frontpageErrorEvent(url, err, now)}, nil
+	}
+	page.Raw = nil
+	if old == nil && p.SkipInitial {
+		return nil, page
+	}
+
+	events := diffFrontpage(old, page, p.Changed)
+	for i := range events {
+		events[i].Url = url
+		events[i].Time = now
+	}
+	return events, page
+}
+
+// save saves the snapshot of the url, the page is not saved if it is nil
+// or ctx is done. The failure is returned as a FrontpageError event.
+func (p *FrontpageWatcher) save(ctx context.Context, url string, page *Frontpage) []FrontpageEvent {
+	if page == nil || ctx.Err() != nil {
+		return nil
+	}
+	if err := p.store().Save(ctx, url, page); err != nil {
+		return []FrontpageEvent{frontpageErrorEvent(url, err, time.Now())}
+	}
+	return nil
+}
+
+func frontpageErrorEvent(url string, err error, now time.Time) FrontpageEvent {
+	return FrontpageEvent{Type: FrontpageError, Url: url, Err: err, Time: now}
+}
+
+// DiffFrontpage returns the added, removed and changed items of cur
+// since old, see FrontpageWatcher. The old may be nil.
+//
+// The events are in the order of the cur items, followed by the removed
+// items in the order of the old items.
+func DiffFrontpage(old, cur *Frontpage) []FrontpageEvent {
+	return diffFrontpage(old, cur, nil)
+}
+
+func diffFrontpage(old, cur *Frontpage, changed func(old, cur *FrontpageItem) bool) []FrontpageEvent {
+	if changed == nil {
+		changed = frontpageItemChanged
+	}
+	var oldItems []FrontpageItem
+	if old != nil {
+		oldItems = old.Items
+	}
+	matched := matchFrontpageItems(oldItems, cur.Items)
+
+	var events []FrontpageEvent
+	used := make([]bool, len(oldItems))
+	for i := range cur.Items {
+		item := &cur.Items[i]
+		j := matched[i]
+		if j < 0 {
+			events = append(events, FrontpageEvent{Type: FrontpageItemAdded, Item: item})
+			continue
+		}
+		used[j] = true
+		if changed(&oldItems[j], item) {
+			events = append(events, FrontpageEvent{Type: FrontpageItemChanged, Item: item, Old: &oldItems[j]})
+		}
+	}
+	for j := range oldItems {
+		if !used[j] {
+			events = append(events, FrontpageEvent{Type: FrontpageItemRemoved, Item: &oldItems[j]})
+		}
+	}
+	return events
+}
+
+// matchFrontpageItems returns the index of the matched old item for each
+// cur item, or -1. The items are matched by id, then by link, and then by
+// xroot, each old item is matched at most once.
+func matchFrontpageItems(old, cur []FrontpageItem) []int {
+	matched := make([]int, len(cur))
+	for i := range matched {
+		matched[i] = -1
+	}
+	used := make([]bool, len(old))
+	keys := []func(item *FrontpageItem) string{
+		func(item *FrontpageItem) string {
+			if item.Id == 0 {
+				return ""
+			}
+			return strconv.Itoa(item.Id)
+		},
+		func(item *FrontpageItem) string { return item.Link },
+		func(item *FrontpageItem) string { return item.XRoot },
+	}
+	for _, key := range keys {
+		index := make(map[string][]int)
+		for j := range old {
+			if k := key(&old[j]); k != "" && !used[j] {
+				index[k] = append(index[k], j)
+			}
+		}
+		for i := range cur {
+			if matched[i] >= 0 {
+				continue
+			}
+			k := key(&cur[i])
+			if k == "" {
+				continue
+			}
+			for n, j := range index[k] {
+				if !used[j] {
+					matched[i], used[j] = j, true
+					index[k] = index[k][n+1:]
+					break
+				}
+			}
+		}
+	}
+	return matched
+}
+
+func frontpageItemChanged(old, cur *FrontpageItem) bool {
+	return old.Title != cur.Title ||
+		old.Link != cur.Link ||
+		old.Type != cur.Type ||
+		old.Img != cur.Img ||
+		old.TextSummary != cur.TextSummary ||
+		old.Description != cur.Description
+}
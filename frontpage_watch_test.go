@@ -0,0 +1,315 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testChangedFrontpage returns a copy of page, with the first item
+// removed, the second item retitled, the link of the third item changed
+// and a new item.
+func testChangedFrontpage(page *Frontpage) *Frontpage {
+	cur := *page
+	cur.Items = append([]FrontpageItem(nil), page.Items[1:]...)
+	cur.Items[0].Title = "New Title"
+	cur.Items[1].Link = "http://www.huffingtonpost.com/new-link.html"
+	cur.Items = append(cur.Items, FrontpageItem{
+		Id:    123,
+		Title: "Added",
+		Link:  "http://www.huffingtonpost.com/added.html",
+	})
+	return &cur
+}
+
+func testFrontpageEventTypes(events []FrontpageEvent) map[FrontpageEventType]int {
+	types := make(map[FrontpageEventType]int)
+	for _, event := range events {
+		types[event.Type]++
+	}
+	return types
+}
+
+func TestDiffFrontpage(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+	cur := testChangedFrontpage(page)
+
+	events := DiffFrontpage(page, cur)
+	if a, b := len(events), 4; a != b {
+		t.Fatalf("expect = %v, got = %v: %v", b, a, events)
+	}
+	if a, b := events[0].Type, FrontpageItemChanged; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[0].Old.Title, page.Items[1].Title; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[1].Type, FrontpageItemChanged; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[1].Item.Link, "http://www.huffingtonpost.com/new-link.html"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[2].Type, FrontpageItemAdded; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[2].Item.Title, "Added"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[3].Type, FrontpageItemRemoved; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[3].Item.Id, page.Items[0].Id; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// No previous snapshot, all items are added.
+	events = DiffFrontpage(nil, page)
+	if a, b := testFrontpageEventTypes(events), map[FrontpageEventType]int{FrontpageItemAdded: len(page.Items)}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if events := DiffFrontpage(page, page); len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
+
+func TestDiffFrontpage_matchByLinkAndXRoot(t *testing.T) {
+	old := &Frontpage{Items: []FrontpageItem{
+		{Id: 1, Title: "a", Link: "http://a.com/1", XRoot: "/DIV[1]"},
+		{Id: 2, Title: "b", Link: "http://a.com/2", XRoot: "/DIV[2]"},
+	}}
+	cur := &Frontpage{Items: []FrontpageItem{
+		{Id: 3, Title: "b", Link: "http://a.com/2", XRoot: "/DIV[9]"},  // same link
+		{Id: 4, Title: "a2", Link: "http://a.com/9", XRoot: "/DIV[1]"}, // same xroot
+	}}
+	events := DiffFrontpage(old, cur)
+	if a, b := len(events), 1; a != b {
+		t.Fatalf("expect = %v, got = %v: %v", b, a, events)
+	}
+	if a, b := events[0].Type, FrontpageItemChanged; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[0].Old.Id, 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+// testFrontpageServer serves the DML of the current page.
+type testFrontpageServer struct {
+	mu   sync.Mutex
+	page *Frontpage
+}
+
+func (p *testFrontpageServer) set(page *Frontpage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.page = page
+}
+
+func (p *testFrontpageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.page.ToDML().EncodeJson(w)
+}
+
+func TestFrontpageWatcher_poll(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+	fs := &testFrontpageServer{page: page}
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	url := "http://www.huffingtonpost.com"
+	watcher := &FrontpageWatcher{
+		Token:   "token",
+		Urls:    []string{url},
+		Options: &Options{Client: testRedirectDoer(ts.URL)},
+	}
+	ctx := context.Background()
+
+	events := watcher.Poll(ctx)
+	if a, b := testFrontpageEventTypes(events), map[FrontpageEventType]int{FrontpageItemAdded: len(page.Items)}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := events[0].Url, url; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if events := watcher.Poll(ctx); len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	fs.set(testChangedFrontpage(page))
+	events = watcher.Poll(ctx)
+	expect := map[FrontpageEventType]int{FrontpageItemAdded: 1, FrontpageItemRemoved: 1, FrontpageItemChanged: 2}
+	if a, b := testFrontpageEventTypes(events), expect; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// The store is shared, a new watcher with SkipInitial continues.
+	fs.set(page)
+	watcher2 := &FrontpageWatcher{
+		Token:       "token",
+		Urls:        []string{url},
+		Options:     watcher.Options,
+		Store:       watcher.Store,
+		SkipInitial: true,
+	}
+	events = watcher2.Poll(ctx)
+	expect = map[FrontpageEventType]int{FrontpageItemAdded: 1, FrontpageItemRemoved: 1, FrontpageItemChanged: 2}
+	if a, b := testFrontpageEventTypes(events), expect; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	watcher3 := &FrontpageWatcher{
+		Token:       "token",
+		Urls:        []string{url},
+		Options:     watcher.Options,
+		SkipInitial: true,
+	}
+	if events := watcher3.Poll(ctx); len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
+
+func TestFrontpageWatcher_pollError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Not authorized API token.","errorCode":401}`, 401)
+	}))
+	defer ts.Close()
+
+	watcher := &FrontpageWatcher{
+		Token:   "token",
+		Urls:    []string{"http://a.com", "http://b.com"},
+		Options: &Options{Client: testRedirectDoer(ts.URL)},
+	}
+	events := watcher.Poll(context.Background())
+	if a, b := len(events), 2; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	for _, event := range events {
+		if event.Type != FrontpageError || event.Err == nil {
+			t.Fatalf("expect error event, got = %v", event)
+		}
+	}
+}
+
+func TestFrontpageWatcher_watch(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+	fs := &testFrontpageServer{page: testChangedFrontpage(page)}
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	url := "http://www.huffingtonpost.com"
+	store := NewMemoryFrontpageStore()
+	store.Save(context.Background(), url, page)
+
+	watcher := &FrontpageWatcher{
+		Token:    "token",
+		Urls:     []string{url},
+		Options:  &Options{Client: testRedirectDoer(ts.URL)},
+		Store:    store,
+		Interval: 10 * time.Millisecond,
+		Jitter:   0.5,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := watcher.Watch(ctx)
+
+	var added int
+	for event := range events {
+		if event.Type == FrontpageItemAdded {
+			added++
+			if a, b := event.Item.Title, "Added"; a != b {
+				t.Fatalf("expect = %v, got = %v", b, a)
+			}
+			cancel()
+		}
+	}
+	if a, b := added, 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFrontpageWatcher_watchUndelivered(t *testing.T) {
+	page := testParseFrontpage(t, testJsonDataFrontpage)
+	fs := &testFrontpageServer{page: testChangedFrontpage(page)}
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	url := "http://www.huffingtonpost.com"
+	store := NewMemoryFrontpageStore()
+	store.Save(context.Background(), url, page)
+
+	watcher := &FrontpageWatcher{
+		Token:   "token",
+		Urls:    []string{url},
+		Options: &Options{Client: testRedirectDoer(ts.URL)},
+		Store:   store,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := watcher.Watch(ctx)
+	<-events
+	cancel()
+	for range events {
+	}
+
+	// The snapshot is not saved, the events are reported again.
+	if old, _ := store.Load(context.Background(), url); old != page {
+		t.Fatal("the snapshot is saved before the events are delivered")
+	}
+	expect := map[FrontpageEventType]int{FrontpageItemAdded: 1, FrontpageItemRemoved: 1, FrontpageItemChanged: 2}
+	if a, b := testFrontpageEventTypes(watcher.Poll(context.Background())), expect; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFrontpageWatcher_interval(t *testing.T) {
+	watcher := &FrontpageWatcher{Interval: time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if d := watcher.interval(); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("invalid interval: %v", d)
+		}
+	}
+	if a, b := (&FrontpageWatcher{}).interval(), DefaultFrontpageWatchInterval; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// The Jitter is clamped, and the interval is at least the min.
+	watcher = &FrontpageWatcher{Interval: 10 * time.Second, Jitter: 5}
+	for i := 0; i < 100; i++ {
+		if d := watcher.interval(); d < time.Second || d > 19*time.Second {
+			t.Fatalf("invalid interval: %v", d)
+		}
+	}
+	if a, b := (&FrontpageWatcher{Interval: time.Millisecond}).interval(), MinFrontpageWatchInterval; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFileFrontpageStore(t *testing.T) {
+	store := &FileFrontpageStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	if page, err := store.Load(ctx, "http://a.com"); err != nil || page != nil {
+		t.Fatalf("expect nil, got = %v, %v", page, err)
+	}
+	page := testParseFrontpage(t, testJsonDataFrontpageOther)
+	if err := store.Save(ctx, "http://a.com", page); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page, got) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", page, got)
+	}
+}
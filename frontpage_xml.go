@@ -0,0 +1,271 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The DML is returned as XML by default, e.g.
+//
+//	<dml>
+//	  <info>
+//	    <title>Breaking News and Opinion on The Huffington Post</title>
+//	    <sourceURL>http://www.huffingtonpost.com</sourceURL>
+//	    ...
+//	  </info>
+//	  <item id="180194704" sp="0.000" fresh="1.000" sr="4.000" type="STORY" xroot="/HTML[1]/...">
+//	    <title>The Austerity Trap and the Jobs Deficit</title>
+//	    <link>http://www.huffingtonpost.com:80/robert-l-borosage/...</link>
+//	    ...
+//	  </item>
+//	</dml>
+//
+// and as JSON with format=json, where the elements are rendered as
+// {"tagName":...,"childNodes":[...]} objects, and the attributes as the
+// fields of the objects. The XML is converted to the JSON rendering, so
+// both formats are decoded by FrontpageDML.ParseJson.
+
+// Parse parses the XML or JSON encoded DML data, the format is detected
+// from the first non-space byte.
+func (p *FrontpageDML) Parse(data []byte) error {
+	if isXmlData(data) {
+		return p.ParseXml(data)
+	}
+	return p.ParseJson(data)
+}
+
+// ParseXml parses the XML-encoded DML data.
+//
+// The data is converted to the JSON rendering of the DML, which is kept
+// in p.Raw, see ParseJson.
+func (p *FrontpageDML) ParseXml(data []byte) error {
+	d, err := dmlXmlToJson(data)
+	if err != nil {
+		return err
+	}
+	return p.ParseJson(d)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, see ParseXml.
+func (p *FrontpageDML) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	node, err := decodeDmlXmlNode(d, start, 0)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return p.ParseJson(data)
+}
+
+// MarshalXML implements xml.Marshaler, it encodes the DML as XML.
+// The attributes are written by nodeAttrs.
+func (p *FrontpageDML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	tagName := p.TagName
+	if tagName == "" {
+		tagName = "dml"
+	}
+	root := xml.StartElement{Name: xml.Name{Local: tagName}}
+	if p.Id != 0 {
+		root.Attr = append(root.Attr, xmlAttr("id", strconv.FormatInt(p.Id, 10)))
+	}
+	if err := e.EncodeToken(root); err != nil {
+		return err
+	}
+	for i, node := range p.ChildNodes {
+		elem := xml.StartElement{Name: xml.Name{Local: node.TagName}, Attr: p.nodeAttrs(i)}
+		if err := e.EncodeToken(elem); err != nil {
+			return err
+		}
+		for _, child := range node.ChildNodes {
+			err := e.EncodeElement(struct {
+				Text string `xml:",chardata"`
+			}{strings.Join(child.ChildNodes, "")}, xml.StartElement{Name: xml.Name{Local: child.TagName}})
+			if err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(elem.End()); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// nodeAttrs returns the attributes of p.ChildNodes[i].
+//
+// The id attribute is written for the item elements, or if it is not zero.
+// The commentCount attribute is written if it is not zero, the other
+// attributes if they are not empty, and then the ItemAttrs by name.
+func (p *FrontpageDML) nodeAttrs(i int) []xml.Attr {
+	node := &p.ChildNodes[i]
+	var attrs []xml.Attr
+	if node.TagName == "item" || node.ItemId != 0 {
+		attrs = append(attrs, xmlAttr("id", strconv.FormatInt(node.ItemId, 10)))
+	}
+	for _, attr := range []xml.Attr{
+		xmlAttr("sp", node.ItemSp),
+		xmlAttr("fresh", node.ItemFresh),
+		xmlAttr("sr", node.ItemSr),
+		xmlAttr("type", node.ItemType),
+		xmlAttr("cluster", node.ItemCluster),
+		xmlAttr("xroot", node.ItemXRoot),
+	} {
+		if attr.Value != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	if node.ItemCommentCount != 0 {
+		attrs = append(attrs, xmlAttr("commentCount", strconv.FormatInt(node.ItemCommentCount, 10)))
+	}
+	for _, name := range sortedKeys(node.ItemAttrs) {
+		attrs = append(attrs, xmlAttr(name, node.ItemAttrs[name]))
+	}
+	return attrs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func xmlAttr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// isXmlData reports whether data is XML, i.e. it begins with '<'.
+func isXmlData(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) != 0 && data[0] == '<'
+}
+
+// dmlXmlToJson converts the XML-encoded DML to the JSON rendering.
+func dmlXmlToJson(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("diffbot: invalid DML XML: %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			node, err := decodeDmlXmlNode(decoder, start, 0)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(node)
+		}
+	}
+}
+
+// decodeDmlXmlNode decodes the element as a JSON rendering node. The
+// attributes become the fields, and the child elements or the text (for
+// the elements without child elements) become the childNodes. The fields
+// of the info and item nodes (at depth 2) are leaves, their inner XML is
+// the text, e.g. the unescaped HTML of a description.
+func decodeDmlXmlNode(d *xml.Decoder, start xml.StartElement, depth int) (map[string]interface{}, error) {
+	node := map[string]interface{}{
+		"tagName": start.Name.Local,
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		node[attr.Name.Local] = attr.Value
+	}
+
+	if depth == 2 {
+		text, err := decodeDmlXmlInner(d)
+		if err != nil {
+			return nil, err
+		}
+		node["childNodes"] = []interface{}{}
+		if text != "" {
+			node["childNodes"] = []interface{}{text}
+		}
+		return node, nil
+	}
+
+	var elems []interface{}
+	var text strings.Builder
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("diffbot: invalid DML XML: %v", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeDmlXmlNode(d, t, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			switch {
+			case len(elems) != 0:
+				node["childNodes"] = elems
+			case text.Len() != 0:
+				node["childNodes"] = []interface{}{text.String()}
+			default:
+				node["childNodes"] = []interface{}{}
+			}
+			return node, nil
+		}
+	}
+}
+
+// decodeDmlXmlInner returns the inner XML of the current element, the
+// text is unescaped and the child elements are written as tags, the
+// void elements (e.g. <br>) are self-closed.
+func decodeDmlXmlInner(d *xml.Decoder) (string, error) {
+	var buf strings.Builder
+	for depth := 0; ; {
+		token, err := d.Token()
+		if err != nil {
+			return "", fmt.Errorf("diffbot: invalid DML XML: %v", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			buf.WriteString("<" + t.Name.Local)
+			for _, attr := range t.Attr {
+				buf.WriteString(" " + attr.Name.Local + `="` + html.EscapeString(attr.Value) + `"`)
+			}
+			if htmlVoidElements[strings.ToLower(t.Name.Local)] {
+				buf.WriteString("/")
+			}
+			buf.WriteString(">")
+		case xml.CharData:
+			buf.Write(t)
+		case xml.EndElement:
+			if depth == 0 {
+				return buf.String(), nil
+			}
+			depth--
+			if !htmlVoidElements[strings.ToLower(t.Name.Local)] {
+				buf.WriteString("</" + t.Name.Local + ">")
+			}
+		}
+	}
+}
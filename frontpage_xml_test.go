@@ -0,0 +1,188 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFrontpageDML_parseXml(t *testing.T) {
+	var dml FrontpageDML
+	if err := dml.Parse([]byte(testXmlDataFrontpage)); err != nil {
+		t.Fatal(err)
+	}
+	if len(dml.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", dml.Warnings)
+	}
+	var page Frontpage
+	if err := page.ParseDML(&dml); err != nil {
+		t.Fatal(err)
+	}
+	page.Raw = nil
+	items := page.Items
+	page.Items = nil
+
+	if !reflect.DeepEqual(testGoldenFrontpage, page) {
+		t.Fatalf("not equal, expect = \n%+v, got = \n%+v", testGoldenFrontpage, page)
+	}
+	if a, b := len(items), 2; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	for i := range items {
+		if a, b := items[i].Description, testGoldenFrontpageXmlDescriptions[i]; a != b {
+			t.Fatalf("expect = %q, got = %q", b, a)
+		}
+		items[i].Description = ""
+		items[i].TextSummary = ""
+		if !reflect.DeepEqual(testGoldenFrontpageItems[i], items[i]) {
+			t.Fatalf("not equal, expect = \n%+v, got = \n%+v", testGoldenFrontpageItems[i], items[i])
+		}
+	}
+	if a, b := dml.ChildNodes[1].ItemCluster, "/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := dml.ChildNodes[1].ItemCommentCount, int64(34); a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFrontpageDML_roundTrip(t *testing.T) {
+	var dml FrontpageDML
+	if err := dml.Parse([]byte(testJsonDataFrontpage)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.MarshalIndent(&dml, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dml2 FrontpageDML
+	if err := dml2.Parse(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dml.ChildNodes, dml2.ChildNodes) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", dml.ChildNodes, dml2.ChildNodes)
+	}
+	if a, b := dml2.TagName, "dml"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	var page, page2 Frontpage
+	if err := page.ParseDML(&dml); err != nil {
+		t.Fatal(err)
+	}
+	if err := page2.ParseDML(&dml2); err != nil {
+		t.Fatal(err)
+	}
+	page.Raw, page2.Raw = nil, nil
+	if !reflect.DeepEqual(page, page2) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", &page, &page2)
+	}
+
+	// And back to XML.
+	data2, err := xml.MarshalIndent(&dml2, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := string(data2), string(data); a != b {
+		t.Fatalf("not equal, expect = \n%s, got = \n%s", b, a)
+	}
+}
+
+func TestFrontpageDML_unmarshalXml(t *testing.T) {
+	var v struct {
+		Dml FrontpageDML `xml:"dml"`
+	}
+	data := "<response>" + testXmlDataFrontpage[len(`<?xml version="1.0" encoding="UTF-8"?>`)+1:] + "</response>"
+	if err := xml.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := len(v.Dml.ChildNodes), 3; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFrontpageDML_parseXmlUnescapedHtml(t *testing.T) {
+	data := `<dml><info><title>t</title><note>a <i>b</i></note></info>` +
+		`<item id="1"><title>x</title>` +
+		`<description><p class="lead">Hello <b>world</b><br>again</p></description>` +
+		`</item></dml>`
+	var dml FrontpageDML
+	if err := dml.Parse([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	var page Frontpage
+	if err := page.ParseDML(&dml); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := page.Items[0].Description, `<p class="lead">Hello <b>world</b><br/>again</p>`; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := page.Other["note"], `a <i>b</i>`; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestFrontpageDML_parseXmlInvalid(t *testing.T) {
+	var dml FrontpageDML
+	if err := dml.Parse([]byte(`<dml><info><title>abc</info>`)); err == nil {
+		t.Fatalf("expect error")
+	}
+}
+
+func TestParseFrontpage_xml(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, testXmlDataFrontpage)
+	}))
+	defer ts.Close()
+
+	page, err := ParseFrontpage("token", "http://www.huffingtonpost.com", &Options{Client: testRedirectDoer(ts.URL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := page.Title, testGoldenFrontpage.Title; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := len(page.Items), 2; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+var testGoldenFrontpageXmlDescriptions = []string{
+	`<a href="http://www.huffingtonpost.com/robert-l-borosage/the-austerity-trap-and-th_b_1524662.html">The Austerity Trap &amp; the Jobs Deficit</a>`,
+	`Bringing Water And Sanitation To Ethiopian Children`,
+}
+
+const testXmlDataFrontpage = `<?xml version="1.0" encoding="UTF-8"?>
+<dml id="0">
+  <info>
+    <title>Breaking News and Opinion on The Huffington Post</title>
+    <sourceType>html</sourceType>
+    <sourceURL>http://www.huffingtonpost.com</sourceURL>
+    <icon>http://www.huffingtonpost.com:80/favicon.ico</icon>
+    <numItems>51</numItems>
+    <numSpamItems>0</numSpamItems>
+  </info>
+  <item id="180194704" sp="0.000" fresh="1.000" sr="4.000" type="STORY" cluster="/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]" commentCount="34" xroot="/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]/DIV[1]">
+    <title>The Austerity Trap and the Jobs Deficit</title>
+    <link>http://www.huffingtonpost.com:80/robert-l-borosage/the-austerity-trap-and-th_b_1524662.html</link>
+    <pubDate>Thu, 17 May 2012 20:06:26 GMT</pubDate>
+    <textSummary>The Austerity Trap and the Jobs Deficit</textSummary>
+    <description><![CDATA[<a href="http://www.huffingtonpost.com/robert-l-borosage/the-austerity-trap-and-th_b_1524662.html">The Austerity Trap &amp; the Jobs Deficit</a>]]></description>
+  </item>
+  <item id="-91871119" sp="0.101" fresh="1.000" sr="4.000" type="STORY" cluster="/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]" xroot="/HTML[1]/BODY[1]/DIV[4]/DIV[3]/DIV[3]/DIV[8]/DIV[1]/DIV[5]/DIV[4]/DIV[1]/DIV[1]/DIV[1]/DIV[2]/DIV[2]">
+    <title>Bringing Water And Sanitation To Ethiopian Children</title>
+    <link>http://at.atwola.com:80/adlink/3.0/5113.1/2298499/1/16/AdId=2520773;BnId=1;link=http://www.huffingtonpost.com/conrad-person/caring-for-our-future-bri_b_1521005.htmlhttp://www.huffingtonpost.com/conrad-person/caring-for-our-future-bri_b_1521005.html</link>
+    <pubDate>Thu, 17 May 2012 20:06:26 GMT</pubDate>
+    <textSummary></textSummary>
+    <description>Bringing Water And Sanitation To Ethiopian Children</description>
+  </item>
+</dml>
+`
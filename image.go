@@ -5,6 +5,7 @@
 package diffbot
 
 import (
+	"context"
 	"encoding/json"
 )
 
@@ -12,54 +13,39 @@ import (
 //
 // See http://diffbot.com/dev/docs/image/
 type Image struct {
-	Title       string                 `json:"title"`
-	NextPage    string                 `json:"nextPage"`
-	AlbumUrl    string                 `json:"albumUrl"`
-	Url         string                 `json:"url"`
-	ResolvedUrl string                 `json:"resolved_url"`
-	Meta        map[string]interface{} `json:"meta,omitempty"`        // Returned with fields.
-	QueryString string                 `json:"querystring,omitempty"` // Returned with fields.
-	Links       []string               `json:"links,omitempty"`       // Returned with fields.
-	Images      []struct {
-		Url           string   `json:"url"`
-		AnchorUrl     string   `json:"anchorUrl"`
-		Mime          string   `json:"mime,omitempty"` // Returned with fields.
-		Caption       string   `json:"caption"`
-		AttrAlt       string   `json:"attrAlt,omitempty"`   // Returned with fields.
-		AttrTitle     string   `json:"attrTitle,omitempty"` // Returned with fields.
-		Date          string   `json:"date"`
-		Size          int      `json:"size"`
-		PixelHeight   int      `json:"pixelHeight"`
-		PixelWidth    int      `json:"pixelWidth"`
-		DisplayHeight int      `json:"displayHeight,omitempty"` // Returned with fields.
-		DisplayWidth  int      `json:"displayWidth",omitempty`  // Returned with fields.
-		Meta          []string `json:"meta"`
-		Faces         []string `json:"faces,omitempty"`  // Returned with fields.
-		Ocr           string   `json:"ocr,omitempty"`    // Returned with fields.
-		Colors        string   `json:"colors,omitempty"` // Returned with fields.
-		XPath         string   `json:"xpath"`
-	} `json:"images"`
+	Title       string                     `json:"title"`
+	NextPage    string                     `json:"nextPage"`
+	AlbumUrl    string                     `json:"albumUrl"`
+	Url         string                     `json:"url"`
+	ResolvedUrl string                     `json:"resolved_url"`
+	Meta        map[string]interface{}     `json:"meta,omitempty"`        // Returned with fields.
+	QueryString string                     `json:"querystring,omitempty"` // Returned with fields.
+	Links       []string                   `json:"links,omitempty"`       // Returned with fields.
+	Images      []ImageItem                `json:"images"`
+	Warnings    []DecodeWarning            `json:"-"` // Mismatched fields, see ParseJson.
+	Raw         json.RawMessage            `json:"-"` // The JSON data, see ParseJson.
+	Extra       map[string]json.RawMessage `json:"-"` // Unrecognised JSON fields, see ParseJson.
 }
 
-// type of Image.Images[?]
-type imageImageType struct {
-	Url           string   `json:"url"`
-	AnchorUrl     string   `json:"anchorUrl"`
-	Mime          string   `json:"mime,omitempty"` // Returned with fields.
-	Caption       string   `json:"caption"`
-	AttrAlt       string   `json:"attrAlt,omitempty"`   // Returned with fields.
-	AttrTitle     string   `json:"attrTitle,omitempty"` // Returned with fields.
-	Date          string   `json:"date"`
-	Size          int      `json:"size"`
-	PixelHeight   int      `json:"pixelHeight"`
-	PixelWidth    int      `json:"pixelWidth"`
-	DisplayHeight int      `json:"displayHeight,omitempty"` // Returned with fields.
-	DisplayWidth  int      `json:"displayWidth",omitempty`  // Returned with fields.
-	Meta          []string `json:"meta"`
-	Faces         []string `json:"faces,omitempty"`  // Returned with fields.
-	Ocr           string   `json:"ocr,omitempty"`    // Returned with fields.
-	Colors        string   `json:"colors,omitempty"` // Returned with fields.
-	XPath         string   `json:"xpath"`
+// ImageItem represents an image of the Image result.
+type ImageItem struct {
+	Url           string      `json:"url"`
+	AnchorUrl     string      `json:"anchorUrl"`
+	Mime          string      `json:"mime,omitempty"` // Returned with fields.
+	Caption       string      `json:"caption"`
+	AttrAlt       string      `json:"attrAlt,omitempty"`   // Returned with fields.
+	AttrTitle     string      `json:"attrTitle,omitempty"` // Returned with fields.
+	Date          string      `json:"date"`
+	Size          int         `json:"size"`
+	PixelHeight   int         `json:"pixelHeight"`
+	PixelWidth    int         `json:"pixelWidth"`
+	DisplayHeight int         `json:"displayHeight,omitempty"` // Returned with fields.
+	DisplayWidth  int         `json:"displayWidth,omitempty"`  // Returned with fields.
+	Meta          []string    `json:"meta"`
+	Faces         ImageFaces  `json:"faces,omitempty"`  // Returned with fields, see ImageFaces.
+	Ocr           ImageOcr    `json:"ocr,omitempty"`    // Returned with fields, see ImageOcr.
+	Colors        ImageColors `json:"colors,omitempty"` // Returned with fields, see ImageColors.
+	XPath         string      `json:"xpath"`
 }
 
 // ParseImage parse a web page and returns its primary image(s).
@@ -230,18 +216,32 @@ type imageImageType struct {
 //	    }
 //	  ]
 //	}
+//
+// The pages of a gallery can be walked with ImageAlbumIterator.
 func ParseImage(token, url string, opt *Options) (*Image, error) {
-	body, err := Diffbot("image", token, url, opt)
+	return ParseImageContext(context.Background(), token, url, opt)
+}
+
+// ParseImageContext like ParseImage function, but carries a context.
+func ParseImageContext(ctx context.Context, token, url string, opt *Options) (*Image, error) {
+	body, err := DiffbotContext(ctx, "image", token, url, opt)
 	if err != nil {
 		return nil, err
 	}
 	var result Image
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := opt.parseJson(ctx, "image", body, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
+// ParseJson parses the JSON-encoded Image data, see DecodeWarning. The
+// unknown fields are errors only in ParseImage with Options.StrictFields.
+func (p *Image) ParseJson(data []byte) (err error) {
+	p.Warnings, p.Raw, p.Extra, err = decodeResultJson(data, p)
+	return err
+}
+
 func (p *Image) String() string {
 	d, _ := json.Marshal(p)
 	return string(d)
@@ -0,0 +1,199 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	urlPkg "net/url"
+)
+
+const defaultImageAlbumMaxPages = 20
+
+func (p *Options) imageAlbumMaxPages() int {
+	if p != nil && p.ImageAlbumMaxPages > 0 {
+		return p.ImageAlbumMaxPages
+	}
+	return defaultImageAlbumMaxPages
+}
+
+// ImageAlbum is the result of all the pages of an image gallery,
+// see ParseImageAlbum.
+type ImageAlbum struct {
+	Title     string          `json:"title"`    // Title of the first page
+	AlbumUrl  string          `json:"albumUrl"` // The first albumUrl of the pages
+	Pages     []string        `json:"pages"`    // URLs of the visited pages
+	Images    []ImageItem     `json:"images"`   // Deduplicated by URL, in the page order
+	Cycle     bool            `json:"cycle,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+	Warnings  []DecodeWarning `json:"-"`
+}
+
+// ImageAlbumIterator walks the pages of an image gallery, following the
+// Image.NextPage links:
+//
+//	it := diffbot.NewImageAlbumIterator(token, url, opt)
+//	for it.Next(ctx) {
+//		for _, img := range it.Page().Images {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// The images of a page whose URL is on a previous page are removed from
+// the page. The walk stops if a next page was already visited (see Cycle),
+// or after opt.ImageAlbumMaxPages pages (see Truncated).
+type ImageAlbumIterator struct {
+	token string
+	opt   *Options
+	next  string
+
+	maxPages  int
+	pages     []string
+	visited   map[string]bool
+	images    map[string]bool
+	page      *Image
+	err       error
+	cycle     bool
+	truncated bool
+}
+
+// NewImageAlbumIterator returns an iterator starting at url.
+func NewImageAlbumIterator(token, url string, opt *Options) *ImageAlbumIterator {
+	return &ImageAlbumIterator{
+		token:    token,
+		opt:      opt,
+		next:     url,
+		maxPages: opt.imageAlbumMaxPages(),
+		visited:  make(map[string]bool),
+		images:   make(map[string]bool),
+	}
+}
+
+// Next fetches the next page, it returns false at the end of the album
+// or on error.
+func (p *ImageAlbumIterator) Next(ctx context.Context) bool {
+	p.page = nil
+	if p.err != nil || p.next == "" {
+		return false
+	}
+	if p.visited[p.next] {
+		p.cycle = true
+		return false
+	}
+	if len(p.pages) >= p.maxPages {
+		p.truncated = true
+		return false
+	}
+
+	url := p.next
+	page, err := ParseImageContext(ctx, p.token, url, p.opt)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.pages = append(p.pages, url)
+	for _, u := range []string{url, page.Url, page.ResolvedUrl} {
+		if u != "" {
+			p.visited[u] = true
+		}
+	}
+
+	images := page.Images[:0:0]
+	for _, img := range page.Images {
+		if img.Url != "" {
+			if p.images[img.Url] {
+				continue
+			}
+			p.images[img.Url] = true
+		}
+		images = append(images, img)
+	}
+	page.Images = images
+
+	p.next = resolveImageNextPage(page, url)
+	p.page = page
+	return true
+}
+
+// Page returns the current page.
+func (p *ImageAlbumIterator) Page() *Image {
+	return p.page
+}
+
+// Pages returns the URLs of the visited pages.
+func (p *ImageAlbumIterator) Pages() []string {
+	return p.pages
+}
+
+// Err returns the error which stopped the iteration.
+func (p *ImageAlbumIterator) Err() error {
+	return p.err
+}
+
+// Cycle reports whether the iteration stopped on a visited page.
+func (p *ImageAlbumIterator) Cycle() bool {
+	return p.cycle
+}
+
+// Truncated reports whether the iteration stopped on the max pages limit.
+func (p *ImageAlbumIterator) Truncated() bool {
+	return p.truncated
+}
+
+// resolveImageNextPage resolves the NextPage link of the page, the
+// relative links are resolved against the page URL.
+func resolveImageNextPage(page *Image, url string) string {
+	if page.NextPage == "" {
+		return ""
+	}
+	base := page.ResolvedUrl
+	if base == "" {
+		base = page.Url
+	}
+	if base == "" {
+		base = url
+	}
+	u, err := urlPkg.Parse(base)
+	if err != nil {
+		return page.NextPage
+	}
+	next, err := u.Parse(page.NextPage)
+	if err != nil {
+		return page.NextPage
+	}
+	return next.String()
+}
+
+// ParseImageAlbum walks the gallery pages from url, and aggregates them
+// into an ImageAlbum, see ImageAlbumIterator.
+func ParseImageAlbum(token, url string, opt *Options) (*ImageAlbum, error) {
+	return ParseImageAlbumContext(context.Background(), token, url, opt)
+}
+
+// ParseImageAlbumContext like ParseImageAlbum function, but carries a context.
+func ParseImageAlbumContext(ctx context.Context, token, url string, opt *Options) (*ImageAlbum, error) {
+	album := &ImageAlbum{Images: []ImageItem{}}
+	it := NewImageAlbumIterator(token, url, opt)
+	for it.Next(ctx) {
+		page := it.Page()
+		if len(it.Pages()) == 1 {
+			album.Title = page.Title
+		}
+		if album.AlbumUrl == "" {
+			album.AlbumUrl = page.AlbumUrl
+		}
+		album.Images = append(album.Images, page.Images...)
+		album.Warnings = append(album.Warnings, page.Warnings...)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	album.Pages = it.Pages()
+	album.Cycle = it.Cycle()
+	album.Truncated = it.Truncated()
+	return album, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func testImageAlbumServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := testJsonDataImageAlbum[r.URL.Query().Get("url")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"Could not download page (404)","errorCode":404}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+}
+
+func testImageAlbumUrls(images []ImageItem) []string {
+	var urls []string
+	for _, img := range images {
+		urls = append(urls, img.Url)
+	}
+	return urls
+}
+
+func TestParseImageAlbum(t *testing.T) {
+	ts := testImageAlbumServer()
+	defer ts.Close()
+
+	opt := &Options{Client: testRedirectDoer(ts.URL)}
+	album, err := ParseImageAlbum("token", "http://example.com/gallery/1", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := album.Title, "Gallery"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := album.AlbumUrl, "http://example.com/gallery"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := album.Pages, []string{
+		"http://example.com/gallery/1",
+		"http://example.com/gallery/2",
+		"http://example.com/gallery/3",
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := testImageAlbumUrls(album.Images), []string{
+		"http://example.com/a.jpg",
+		"http://example.com/b.jpg",
+		"http://example.com/c.jpg",
+		"http://example.com/d.jpg",
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if !album.Cycle || album.Truncated {
+		t.Fatalf("expect cycle, got = %v, %v", album.Cycle, album.Truncated)
+	}
+
+	opt.ImageAlbumMaxPages = 2
+	if album, err = ParseImageAlbum("token", "http://example.com/gallery/1", opt); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := len(album.Pages), 2; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if album.Cycle || !album.Truncated {
+		t.Fatalf("expect truncated, got = %v, %v", album.Cycle, album.Truncated)
+	}
+
+	// A single page.
+	if album, err = ParseImageAlbum("token", "http://example.com/single", opt); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := len(album.Images), 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if album.Cycle || album.Truncated {
+		t.Fatalf("unexpected stop: %v, %v", album.Cycle, album.Truncated)
+	}
+
+	if _, err := ParseImageAlbum("token", "http://example.com/broken/1", opt); err == nil {
+		t.Fatalf("expect error, got nil")
+	} else if apiErr, ok := err.(*Error); !ok || apiErr.ErrCode != 404 {
+		t.Fatalf("expect 404 error, got = %v", err)
+	}
+}
+
+func TestImageAlbumIterator(t *testing.T) {
+	ts := testImageAlbumServer()
+	defer ts.Close()
+
+	it := NewImageAlbumIterator("token", "http://example.com/gallery/1", &Options{Client: testRedirectDoer(ts.URL)})
+	var pages [][]string
+	for it.Next(context.Background()) {
+		pages = append(pages, testImageAlbumUrls(it.Page().Images))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := pages, [][]string{
+		{"http://example.com/a.jpg", "http://example.com/b.jpg"},
+		{"http://example.com/c.jpg"},
+		{"http://example.com/d.jpg"},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if it.Page() != nil || it.Next(context.Background()) {
+		t.Fatal("expect the end of the album")
+	}
+
+	// The error stops the iteration.
+	it = NewImageAlbumIterator("token", "http://example.com/broken/1", &Options{Client: testRedirectDoer(ts.URL)})
+	if !it.Next(context.Background()) {
+		t.Fatal(it.Err())
+	}
+	if it.Next(context.Background()) || it.Err() == nil {
+		t.Fatalf("expect error, got nil")
+	}
+}
+
+var testJsonDataImageAlbum = map[string]string{
+	"http://example.com/gallery/1": `{
+		"title": "Gallery",
+		"url": "http://example.com/gallery/1",
+		"albumUrl": "http://example.com/gallery",
+		"nextPage": "/gallery/2",
+		"images": [{"url": "http://example.com/a.jpg"}, {"url": "http://example.com/b.jpg"}]
+	}`,
+	"http://example.com/gallery/2": `{
+		"title": "Gallery (2)",
+		"url": "http://example.com/gallery/2",
+		"nextPage": "3",
+		"images": [{"url": "http://example.com/b.jpg"}, {"url": "http://example.com/c.jpg"}]
+	}`,
+	"http://example.com/gallery/3": `{
+		"title": "Gallery (3)",
+		"url": "http://example.com/gallery/3",
+		"nextPage": "http://example.com/gallery/1",
+		"images": [{"url": "http://example.com/a.jpg"}, {"url": "http://example.com/d.jpg"}]
+	}`,
+
+	"http://example.com/single": `{"title": "Single", "images": [{"url": "http://example.com/a.jpg"}]}`,
+
+	"http://example.com/broken/1": `{"nextPage": "http://example.com/broken/2", "images": []}`,
+}
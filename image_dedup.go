@@ -0,0 +1,294 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// DefaultImageHashThreshold is the default max distance of the hashes
+	// of near-duplicate images, see ImageDeduper.
+	DefaultImageHashThreshold = 6
+
+	// MaxImageHashPixels is the max width x height of an image decoded
+	// by ImageDeduper, the larger images are not decoded.
+	MaxImageHashPixels = 40 << 20
+)
+
+// ImageHash is a 64-bit perceptual hash of an image, the similar images
+// have hashes of a small Hamming distance.
+type ImageHash uint64
+
+// Distance returns the Hamming distance of the hashes.
+func (p ImageHash) Distance(q ImageHash) int {
+	return bits.OnesCount64(uint64(p ^ q))
+}
+
+func (p ImageHash) String() string {
+	return fmt.Sprintf("%016x", uint64(p))
+}
+
+// AverageHash returns the aHash of img: the image is reduced to 8x8 gray
+// pixels, and each bit is set if the pixel is brighter than the mean.
+func AverageHash(img image.Image) ImageHash {
+	pixels := grayThumbnail(img, 8, 8)
+	var mean float64
+	for _, v := range pixels {
+		mean += v
+	}
+	mean /= float64(len(pixels))
+
+	var hash ImageHash
+	for i, v := range pixels {
+		if v > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// DifferenceHash returns the dHash of img: the image is reduced to 9x8
+// gray pixels, and each bit is set if a pixel is brighter than its right
+// neighbour.
+func DifferenceHash(img image.Image) ImageHash {
+	pixels := grayThumbnail(img, 9, 8)
+	var hash ImageHash
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if pixels[y*9+x] > pixels[y*9+x+1] {
+				hash |= 1 << uint(y*8+x)
+			}
+		}
+	}
+	return hash
+}
+
+// grayThumbnail reduces img to w x h gray pixels, each is the mean
+// luminance of its area of img.
+func grayThumbnail(img image.Image, w, h int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, w*h)
+	counts := make([]float64, w*h)
+	dx, dy := bounds.Dx(), bounds.Dy()
+	if dx == 0 || dy == 0 {
+		return sums
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ty := (y - bounds.Min.Y) * h / dy
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			tx := (x - bounds.Min.X) * w / dx
+			r, g, b, _ := img.At(x, y).RGBA()
+			// The ITU-R BT.601 luma, like color.GrayModel.
+			sums[ty*w+tx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[ty*w+tx]++
+		}
+	}
+	for i := range sums {
+		if counts[i] != 0 {
+			sums[i] /= counts[i]
+		}
+	}
+	return sums
+}
+
+// ImageCluster is a group of near-duplicate images, see ImageDeduper.
+type ImageCluster struct {
+	Canonical *ImageItem   // The largest image of the cluster
+	Items     []*ImageItem // All the images, including Canonical, in the input order
+	AHash     ImageHash    // The aHash of Canonical
+	DHash     ImageHash    // The dHash of Canonical
+	Err       error        // The download or decode failure of a single image cluster
+}
+
+// ImageDeduper clusters the near-duplicate images, e.g. the same image
+// at different sizes or URLs.
+type ImageDeduper struct {
+	// Downloader downloads the images with its Client, Concurrency and
+	// MaxBytes, the Sink is not used. A zero ImageDownloader if nil.
+	Downloader *ImageDownloader
+
+	// Threshold is the max distance of both the aHash and the dHash of
+	// near-duplicate images, DefaultImageHashThreshold if zero, and
+	// only the identical hashes if negative.
+	Threshold int
+
+	// ThumbnailUrl returns the URL of a smaller version of the item image,
+	// the item Url is used if nil.
+	ThumbnailUrl func(item *ImageItem) string
+}
+
+func (p *ImageDeduper) downloader() *ImageDownloader {
+	if p.Downloader == nil {
+		return &ImageDownloader{}
+	}
+	return p.Downloader
+}
+
+func (p *ImageDeduper) threshold() int {
+	switch {
+	case p.Threshold < 0:
+		return 0
+	case p.Threshold == 0:
+		return DefaultImageHashThreshold
+	}
+	return p.Threshold
+}
+
+// imageHashes is the hashes of an image item.
+type imageHashes struct {
+	aHash, dHash  ImageHash
+	width, height int // The decoded size of the thumbnail
+	err           error
+}
+
+// Dedup downloads the thumbnails of the images, and groups the
+// near-duplicates into clusters, in the order of the first image of each
+// cluster. The near-duplicates of a near-duplicate are in the same cluster.
+//
+// The thumbnails are decoded with the standard image packages (GIF, JPEG
+// and PNG, and the other formats registered with image.RegisterFormat).
+// Without ThumbnailUrl, the image itself is the thumbnail, and the images
+// whose Size is larger than the MaxBytes of Downloader are not downloaded.
+// The images larger than MaxImageHashPixels are not decoded. The images
+// which can not be downloaded or decoded are single image clusters with Err.
+//
+// The canonical image of a cluster is the one with the largest
+// PixelWidth x PixelHeight if all the images of the cluster have them,
+// otherwise the one with the largest decoded thumbnail.
+func (p *ImageDeduper) Dedup(ctx context.Context, items []ImageItem) []ImageCluster {
+	hashes := p.hashImages(ctx, items)
+
+	// Union the pairs of near-duplicates.
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	threshold := p.threshold()
+	for i := range items {
+		if hashes[i].err != nil {
+			continue
+		}
+		for j := i + 1; j < len(items); j++ {
+			if hashes[j].err != nil {
+				continue
+			}
+			if hashes[i].aHash.Distance(hashes[j].aHash) <= threshold &&
+				hashes[i].dHash.Distance(hashes[j].dHash) <= threshold {
+				if a, b := find(i), find(j); a != b {
+					parent[b] = a
+				}
+			}
+		}
+	}
+
+	var clusters []ImageCluster
+	var members [][]int
+	index := make(map[int]int)
+	for i := range items {
+		root := find(i)
+		n, ok := index[root]
+		if !ok {
+			n = len(clusters)
+			index[root] = n
+			clusters = append(clusters, ImageCluster{Err: hashes[i].err})
+			members = append(members, nil)
+		}
+		clusters[n].Items = append(clusters[n].Items, &items[i])
+		members[n] = append(members[n], i)
+	}
+
+	for n, c := range members {
+		// The same kind of size is compared for all the images.
+		declared := true
+		for _, i := range c {
+			if items[i].PixelWidth == 0 || items[i].PixelHeight == 0 {
+				declared = false
+			}
+		}
+		area := func(i int) int {
+			if declared {
+				return items[i].PixelWidth * items[i].PixelHeight
+			}
+			return hashes[i].width * hashes[i].height
+		}
+		canonical := c[0]
+		for _, i := range c[1:] {
+			if area(i) > area(canonical) {
+				canonical = i
+			}
+		}
+		clusters[n].Canonical = &items[canonical]
+		clusters[n].AHash = hashes[canonical].aHash
+		clusters[n].DHash = hashes[canonical].dHash
+	}
+	return clusters
+}
+
+// hashImages downloads and hashes the images concurrently.
+func (p *ImageDeduper) hashImages(ctx context.Context, items []ImageItem) []imageHashes {
+	hashes := make([]imageHashes, len(items))
+	downloader := p.downloader()
+	maxBytes := downloader.maxBytes()
+	sem := make(chan struct{}, downloader.concurrency())
+
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		go func(item *ImageItem, h *imageHashes) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				h.err = ctx.Err()
+				return
+			}
+			url := item.Url
+			if p.ThumbnailUrl != nil {
+				url = p.ThumbnailUrl(item)
+			} else if int64(item.Size) > maxBytes {
+				h.err = fmt.Errorf("diffbot: image %s is larger than %d bytes", url, maxBytes)
+				return
+			}
+			data, err := downloadImage(ctx, downloader.client(), url, maxBytes)
+			if err != nil {
+				h.err = err
+				return
+			}
+			// The small data may be a huge image, check the size first.
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				h.err = fmt.Errorf("diffbot: image %s: %v", url, err)
+				return
+			}
+			if int64(config.Width)*int64(config.Height) > MaxImageHashPixels {
+				h.err = fmt.Errorf("diffbot: image %s is larger than %d pixels", url, MaxImageHashPixels)
+				return
+			}
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				h.err = fmt.Errorf("diffbot: image %s: %v", url, err)
+				return
+			}
+			h.aHash, h.dHash = AverageHash(img), DifferenceHash(img)
+			h.width, h.height = img.Bounds().Dx(), img.Bounds().Dy()
+		}(&items[i], &hashes[i])
+	}
+	wg.Wait()
+	return hashes
+}
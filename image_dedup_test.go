@@ -0,0 +1,190 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testPatternImage draws a pattern of width x height, the pattern does
+// not depend on the size. If invert is true, the pattern is mirrored.
+func testPatternImage(width, height int, invert bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			if invert {
+				fx = 1 - fx
+			}
+			v := uint8(255 * fx)
+			if fx > 0.3 && fx < 0.6 && fy > 0.2 && fy < 0.5 {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{v, uint8(255 * fy), v, 255})
+		}
+	}
+	return img
+}
+
+func testEncodeImageData(t *testing.T, img image.Image, format string) []byte {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageHash(t *testing.T) {
+	large := testPatternImage(128, 96, false)
+	small := testPatternImage(32, 24, false)
+	other := testPatternImage(128, 96, true)
+
+	if d := AverageHash(large).Distance(AverageHash(small)); d > 2 {
+		t.Fatalf("aHash distance of the resized image: %d", d)
+	}
+	if d := DifferenceHash(large).Distance(DifferenceHash(small)); d > 2 {
+		t.Fatalf("dHash distance of the resized image: %d", d)
+	}
+	if d := AverageHash(large).Distance(AverageHash(other)); d <= DefaultImageHashThreshold {
+		t.Fatalf("aHash distance of the other image: %d", d)
+	}
+	if d := DifferenceHash(large).Distance(DifferenceHash(other)); d <= DefaultImageHashThreshold {
+		t.Fatalf("dHash distance of the other image: %d", d)
+	}
+
+	if a, b := ImageHash(0xf0).Distance(0x0f), 8; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := ImageHash(0xff).String(), "00000000000000ff"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := AverageHash(image.NewGray(image.Rect(0, 0, 0, 0))), ImageHash(0); a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestImageDeduper(t *testing.T) {
+	is := &testImageServer{images: map[string][]byte{
+		"/large.png":  testEncodeImageData(t, testPatternImage(128, 96, false), "png"),
+		"/small.jpg":  testEncodeImageData(t, testPatternImage(32, 24, false), "jpeg"),
+		"/medium.jpg": testEncodeImageData(t, testPatternImage(64, 48, false), "jpeg"),
+		"/other.png":  testEncodeImageData(t, testPatternImage(128, 96, true), "png"),
+		"/text.txt":   []byte("not an image"),
+	}}
+	ts := httptest.NewServer(is)
+	defer ts.Close()
+
+	items := []ImageItem{
+		{Url: ts.URL + "/small.jpg", PixelWidth: 32, PixelHeight: 24},
+		{Url: ts.URL + "/other.png", PixelWidth: 128, PixelHeight: 96},
+		{Url: ts.URL + "/large.png", PixelWidth: 128, PixelHeight: 96},
+		{Url: ts.URL + "/text.txt"},
+		{Url: ts.URL + "/medium.jpg"},
+		{Url: ts.URL + "/missing.png"},
+	}
+	deduper := &ImageDeduper{Downloader: &ImageDownloader{Concurrency: 2}}
+	clusters := deduper.Dedup(context.Background(), items)
+
+	if a, b := len(clusters), 4; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	c := clusters[0]
+	if c.Err != nil || len(c.Items) != 3 {
+		t.Fatalf("unexpected cluster: %v, %d items", c.Err, len(c.Items))
+	}
+	if a, b := c.Items[0], &items[0]; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := c.Items[2], &items[4]; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := c.Canonical, &items[2]; a != b {
+		t.Fatalf("expect = %v, got = %v", b.Url, a.Url)
+	}
+	if c.AHash == 0 || c.DHash == 0 {
+		t.Fatalf("invalid hashes: %v, %v", c.AHash, c.DHash)
+	}
+
+	if c := clusters[1]; c.Err != nil || len(c.Items) != 1 || c.Canonical != &items[1] {
+		t.Fatalf("unexpected cluster: %v, %v", c.Err, c.Items)
+	}
+	for i, c := range clusters[2:] {
+		if c.Err == nil || len(c.Items) != 1 || c.Canonical != &items[3+i*2] {
+			t.Fatalf("expect error cluster, got = %v, %v", c.Err, c.Items)
+		}
+	}
+
+	// The canonical image without metadata is the largest decoded image.
+	clusters = deduper.Dedup(context.Background(), []ImageItem{
+		{Url: ts.URL + "/small.jpg"},
+		{Url: ts.URL + "/medium.jpg"},
+	})
+	if a, b := len(clusters), 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := clusters[0].Canonical.Url, ts.URL+"/medium.jpg"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// The decoded sizes are compared if an image has no metadata.
+	clusters = deduper.Dedup(context.Background(), []ImageItem{
+		{Url: ts.URL + "/small.jpg", PixelWidth: 1000, PixelHeight: 1000},
+		{Url: ts.URL + "/medium.jpg"},
+	})
+	if a, b := clusters[0].Canonical.Url, ts.URL+"/medium.jpg"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// The images larger than MaxBytes are not downloaded, though the
+	// data is small.
+	clusters = deduper.Dedup(context.Background(), []ImageItem{
+		{Url: ts.URL + "/large.png", Size: DefaultMaxImageBytes + 1},
+	})
+	if c := clusters[0]; c.Err == nil || c.Canonical != c.Items[0] {
+		t.Fatalf("expect error cluster, got = %v", c.Err)
+	}
+
+	// The images larger than MaxImageHashPixels are not decoded, though
+	// the data is small.
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)), nil); err != nil {
+		t.Fatal(err)
+	}
+	huge := buf.Bytes()
+	copy(huge[6:10], []byte{0xff, 0xff, 0xff, 0xff}) // The 65535x65535 screen
+	is.images["/huge.gif"] = huge
+	clusters = deduper.Dedup(context.Background(), []ImageItem{{Url: ts.URL + "/huge.gif"}})
+	if c := clusters[0]; c.Err == nil || !strings.Contains(c.Err.Error(), "pixels") {
+		t.Fatalf("expect too large error, got = %v", c.Err)
+	}
+
+	// The thumbnails are downloaded instead of the images.
+	deduper.ThumbnailUrl = func(item *ImageItem) string {
+		return ts.URL + "/small.jpg"
+	}
+	clusters = deduper.Dedup(context.Background(), []ImageItem{
+		{Url: "http://example.com/a.png"},
+		{Url: "http://example.com/b.png"},
+	})
+	if a, b := len(clusters), 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
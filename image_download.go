@@ -0,0 +1,351 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultImageDownloadConcurrency = 4
+
+	// DefaultMaxImageBytes is the default max size of a downloaded image.
+	DefaultMaxImageBytes = 32 << 20
+)
+
+// ImageSink stores the downloaded images, see ImageDownloader.
+type ImageSink interface {
+	// Put stores the image data, name is the SHA-256 of the data
+	// with the extension of the MIME type, e.g. "0a1b...ff.jpg".
+	Put(ctx context.Context, name string, data []byte, mimeType string) error
+}
+
+// MemoryImageSink is an ImageSink in memory.
+type MemoryImageSink struct {
+	mu     sync.Mutex
+	images map[string][]byte
+}
+
+// NewMemoryImageSink returns an empty MemoryImageSink.
+func NewMemoryImageSink() *MemoryImageSink {
+	return &MemoryImageSink{images: make(map[string][]byte)}
+}
+
+func (p *MemoryImageSink) Put(ctx context.Context, name string, data []byte, mimeType string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.images[name] = data
+	return nil
+}
+
+// Get returns the data of the image name, or nil if it is missing.
+func (p *MemoryImageSink) Get(name string) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.images[name]
+}
+
+// Len returns the number of the images.
+func (p *MemoryImageSink) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.images)
+}
+
+// DirImageSink is an ImageSink which writes each image as a file
+// in the Dir directory. The existing image files are not written again,
+// they have the same data as the name is the SHA-256 of the data.
+type DirImageSink struct {
+	Dir string
+}
+
+func (p *DirImageSink) Put(ctx context.Context, name string, data []byte, mimeType string) error {
+	filename := filepath.Join(p.Dir, name)
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return err
+	}
+	// Write a unique temp file and rename, so a crash does not leave a
+	// partial image, and the concurrent puts of the same image do not
+	// write the same temp file.
+	f, err := os.CreateTemp(p.Dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), filename); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// ImageDownloadError is an HTTP error of the image host, it is not an
+// error of the Diffbot API, so it does not match the sentinel errors.
+type ImageDownloadError struct {
+	Url        string // The image URL
+	StatusCode int    // HTTP status code of the response
+	Status     string // HTTP status of the response, e.g. "404 Not Found"
+}
+
+func (p *ImageDownloadError) Error() string {
+	return "diffbot: download image " + p.Url + ": " + p.Status
+}
+
+// Retryable reports whether the same download may succeed later.
+func (p *ImageDownloadError) Retryable() bool {
+	switch {
+	case p.StatusCode == 408 || p.StatusCode == 429:
+		return true
+	case p.StatusCode >= 500 && p.StatusCode != 501:
+		return true
+	}
+	return false
+}
+
+// ImageMismatch is a difference between the extracted metadata of an
+// image and the downloaded file.
+type ImageMismatch struct {
+	Field    string // "mime", "pixelWidth", "pixelHeight" or "size"
+	Expected string // The extracted value
+	Got      string // The value of the downloaded file
+}
+
+func (p ImageMismatch) String() string {
+	return fmt.Sprintf("%s: expect = %s, got = %s", p.Field, p.Expected, p.Got)
+}
+
+// ImageDownload is the result of an image download.
+type ImageDownload struct {
+	Item       *ImageItem
+	Mime       string          // Detected from the data
+	Width      int             // Zero if the format is not decoded
+	Height     int             // Zero if the format is not decoded
+	Size       int             // Size of the data in bytes
+	Sha256     string          // Hex encoded SHA-256 of the data
+	Name       string          // Name in the Sink, empty without Sink
+	Mismatches []ImageMismatch // The extracted metadata mismatches
+	Err        error           // The download or sink failure
+}
+
+// ImageDownloader downloads images with bounded concurrency, and verifies
+// them against the extracted metadata.
+type ImageDownloader struct {
+	// Client downloads the images, http.DefaultClient if nil.
+	Client Doer
+
+	// Sink stores the downloaded images, the images are not kept if nil.
+	Sink ImageSink
+
+	// Concurrency is the max number of downloads at a time, default is 4.
+	Concurrency int
+
+	// MaxBytes limits the size of an image, DefaultMaxImageBytes if zero.
+	MaxBytes int64
+}
+
+func (p *ImageDownloader) client() Doer {
+	if p.Client == nil {
+		return http.DefaultClient
+	}
+	return p.Client
+}
+
+func (p *ImageDownloader) concurrency() int {
+	if p.Concurrency > 0 {
+		return p.Concurrency
+	}
+	return defaultImageDownloadConcurrency
+}
+
+func (p *ImageDownloader) maxBytes() int64 {
+	if p.MaxBytes > 0 {
+		return p.MaxBytes
+	}
+	return DefaultMaxImageBytes
+}
+
+// Download downloads the images, the result keeps the order of items.
+//
+// The MIME type is detected with http.DetectContentType, and the
+// dimensions of the GIF, JPEG and PNG images are decoded. They are
+// compared with the Mime, PixelWidth, PixelHeight and Size of the item,
+// if they are set, and the differences are reported in Mismatches. The
+// mismatched images are still written to the Sink.
+func (p *ImageDownloader) Download(ctx context.Context, items []ImageItem) []ImageDownload {
+	result := make([]ImageDownload, len(items))
+	sem := make(chan struct{}, p.concurrency())
+
+	var wg sync.WaitGroup
+	for i := range items {
+		result[i].Item = &items[i]
+		wg.Add(1)
+		go func(r *ImageDownload) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				return
+			}
+			p.download(ctx, r)
+		}(&result[i])
+	}
+	wg.Wait()
+	return result
+}
+
+func (p *ImageDownloader) download(ctx context.Context, r *ImageDownload) {
+	data, err := downloadImage(ctx, p.client(), r.Item.Url, p.maxBytes())
+	if err != nil {
+		r.Err = err
+		return
+	}
+	sum := sha256.Sum256(data)
+	r.Sha256 = hex.EncodeToString(sum[:])
+	r.Size = len(data)
+	r.Mime = http.DetectContentType(data)
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		r.Width, r.Height = config.Width, config.Height
+	}
+	r.Mismatches = verifyImage(r)
+
+	if p.Sink != nil {
+		r.Name = r.Sha256 + imageExt(r.Mime)
+		if err := p.Sink.Put(ctx, r.Name, data, r.Mime); err != nil {
+			r.Err = err
+		}
+	}
+}
+
+// verifyImage compares the downloaded image with the item metadata.
+func verifyImage(r *ImageDownload) []ImageMismatch {
+	var mismatches []ImageMismatch
+	if r.Item.Mime != "" && !sameMimeType(r.Item.Mime, r.Mime) {
+		mismatches = append(mismatches, ImageMismatch{"mime", r.Item.Mime, r.Mime})
+	}
+	if r.Width != 0 && r.Item.PixelWidth != 0 && r.Item.PixelWidth != r.Width {
+		mismatches = append(mismatches, ImageMismatch{
+			"pixelWidth", strconv.Itoa(r.Item.PixelWidth), strconv.Itoa(r.Width),
+		})
+	}
+	if r.Height != 0 && r.Item.PixelHeight != 0 && r.Item.PixelHeight != r.Height {
+		mismatches = append(mismatches, ImageMismatch{
+			"pixelHeight", strconv.Itoa(r.Item.PixelHeight), strconv.Itoa(r.Height),
+		})
+	}
+	if r.Item.Size != 0 && r.Item.Size != r.Size {
+		mismatches = append(mismatches, ImageMismatch{
+			"size", strconv.Itoa(r.Item.Size), strconv.Itoa(r.Size),
+		})
+	}
+	return mismatches
+}
+
+// sameMimeType reports whether a and b are the same media type, the
+// parameters and the case are ignored. The "image/jpg" is "image/jpeg".
+func sameMimeType(a, b string) bool {
+	normalize := func(s string) string {
+		if t, _, err := mime.ParseMediaType(s); err == nil {
+			s = t
+		}
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "image/jpg" || s == "image/pjpeg" {
+			s = "image/jpeg"
+		}
+		return s
+	}
+	return normalize(a) == normalize(b)
+}
+
+// imageExt returns the file extension of the image MIME type.
+func imageExt(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/bmp":
+		return ".bmp"
+	}
+	return ""
+}
+
+// downloadImage downloads the url, the data is limited to maxBytes.
+//
+// The failures to send the request or to read the response are returned
+// as *TransportError, and the HTTP errors as *ImageDownloadError.
+func downloadImage(ctx context.Context, client Doer, url string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &TransportError{Method: "download", Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ImageDownloadError{Url: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, &TransportError{Method: "download", Err: err}
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("diffbot: image %s is larger than %d bytes", url, maxBytes)
+	}
+	return data, nil
+}
+
+// ImageItems converts the article images to image items, e.g. for
+// ImageDownloader.
+func (p *Article) ImageItems() []ImageItem {
+	items := make([]ImageItem, len(p.Images))
+	for i, img := range p.Images {
+		items[i] = ImageItem{
+			Url:         img.Url,
+			Caption:     img.Caption,
+			PixelWidth:  img.PixelWidth,
+			PixelHeight: img.PixelHeight,
+		}
+	}
+	return items
+}
@@ -0,0 +1,270 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testEncodeImage(t *testing.T, format string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testImageServer serves the images by path, and records the max
+// number of concurrent requests.
+type testImageServer struct {
+	images    map[string][]byte
+	mu        sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (p *testImageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	if p.active++; p.active > p.maxActive {
+		p.maxActive = p.active
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	data, ok := p.images[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+func TestImageDownloader(t *testing.T) {
+	pngData := testEncodeImage(t, "png", 4, 3)
+	jpegData := testEncodeImage(t, "jpeg", 8, 6)
+	is := &testImageServer{images: map[string][]byte{
+		"/a.png":  pngData,
+		"/b.jpg":  jpegData,
+		"/c.png":  pngData,
+		"/d.txt":  []byte("not an image"),
+		"/e.jpeg": jpegData,
+	}}
+	ts := httptest.NewServer(is)
+	defer ts.Close()
+
+	items := []ImageItem{
+		{Url: ts.URL + "/a.png", Mime: "image/png", PixelWidth: 4, PixelHeight: 3, Size: len(pngData)},
+		{Url: ts.URL + "/b.jpg", Mime: "image/jpg; charset=binary", PixelWidth: 8, PixelHeight: 6},
+		{Url: ts.URL + "/c.png", Mime: "image/jpeg", PixelWidth: 40, PixelHeight: 3, Size: 1},
+		{Url: ts.URL + "/d.txt", Mime: "image/png", PixelWidth: 40},
+		{Url: ts.URL + "/missing.png"},
+		{Url: ts.URL + "/e.jpeg"},
+	}
+	sink := NewMemoryImageSink()
+	downloader := &ImageDownloader{Sink: sink, Concurrency: 2}
+	result := downloader.Download(context.Background(), items)
+
+	if a, b := len(result), len(items); a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if is.maxActive > 2 {
+		t.Fatalf("too many concurrent downloads: %d", is.maxActive)
+	}
+
+	r := result[0]
+	sum := sha256.Sum256(pngData)
+	if r.Err != nil || len(r.Mismatches) != 0 {
+		t.Fatalf("unexpected result: %v, %v", r.Err, r.Mismatches)
+	}
+	if a, b := r.Sha256, hex.EncodeToString(sum[:]); a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := r.Name, r.Sha256+".png"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := [3]int{r.Width, r.Height, r.Size}, [3]int{4, 3, len(pngData)}; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if !bytes.Equal(sink.Get(r.Name), pngData) {
+		t.Fatal("the image is not in the sink")
+	}
+
+	if r := result[1]; r.Err != nil || len(r.Mismatches) != 0 || r.Mime != "image/jpeg" {
+		t.Fatalf("unexpected result: %v, %v, %v", r.Mime, r.Err, r.Mismatches)
+	}
+	if a, b := result[2].Mismatches, []ImageMismatch{
+		{"mime", "image/jpeg", "image/png"},
+		{"pixelWidth", "40", "4"},
+		{"size", "1", strconv.Itoa(len(pngData))},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := result[3].Mismatches, []ImageMismatch{
+		{"mime", "image/png", "text/plain; charset=utf-8"},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if r := result[4]; r.Err == nil || r.Name != "" {
+		t.Fatalf("expect error, got = %v, %v", r.Err, r.Name)
+	}
+	var downloadErr *ImageDownloadError
+	if !errors.As(result[4].Err, &downloadErr) {
+		t.Fatalf("expect *ImageDownloadError, got = %T", result[4].Err)
+	}
+	if a, b := [2]interface{}{downloadErr.StatusCode, downloadErr.Url}, [2]interface{}{404, ts.URL + "/missing.png"}; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := result[4].Err.Error(), "diffbot: download image "+ts.URL+"/missing.png: 404 Not Found"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	// The image host errors are not the Diffbot API errors.
+	if errors.Is(result[4].Err, ErrNotFound) || errors.Is(result[4].Err, ErrUnauthorized) || downloadErr.Retryable() {
+		t.Fatalf("unexpected error match: %v", result[4].Err)
+	}
+	if r := result[5]; r.Err != nil || len(r.Mismatches) != 0 {
+		t.Fatalf("unexpected result: %v, %v", r.Err, r.Mismatches)
+	}
+
+	// The same data is stored once.
+	if a, b := sink.Len(), 3; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
+
+func TestImageDownloader_dirSink(t *testing.T) {
+	pngData := testEncodeImage(t, "png", 4, 3)
+	ts := httptest.NewServer(&testImageServer{images: map[string][]byte{"/a.png": pngData}})
+	defer ts.Close()
+
+	dir := filepath.Join(t.TempDir(), "images")
+	downloader := &ImageDownloader{Sink: &DirImageSink{Dir: dir}, MaxBytes: 1 << 10}
+	result := downloader.Download(context.Background(), []ImageItem{{Url: ts.URL + "/a.png"}})
+	if err := result[0].Err; err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, result[0].Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pngData) {
+		t.Fatal("invalid image file")
+	}
+
+	downloader.MaxBytes = 10
+	if err := downloader.Download(context.Background(), []ImageItem{{Url: ts.URL + "/a.png"}})[0].Err; err == nil {
+		t.Fatal("expect error, got nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := downloader.Download(ctx, []ImageItem{{Url: ts.URL + "/a.png"}})[0].Err; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect = %v, got = %v", context.Canceled, err)
+	}
+}
+
+func TestImageDownloader_dirSinkDuplicates(t *testing.T) {
+	pngData := testEncodeImage(t, "png", 4, 3)
+	ts := httptest.NewServer(&testImageServer{images: map[string][]byte{"/a.png": pngData}})
+	defer ts.Close()
+
+	// The same image is downloaded and put concurrently.
+	dir := t.TempDir()
+	downloader := &ImageDownloader{Sink: &DirImageSink{Dir: dir}, Concurrency: 4}
+	items := []ImageItem{{Url: ts.URL + "/a.png"}, {Url: ts.URL + "/a.png"}, {Url: ts.URL + "/a.png"}}
+	for _, r := range downloader.Download(context.Background(), items) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := len(files), 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pngData) {
+		t.Fatal("invalid image file")
+	}
+}
+
+func TestDownloadImage_transportError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL + "/a.png"
+	ts.Close()
+
+	_, err := downloadImage(context.Background(), http.DefaultClient, url, DefaultMaxImageBytes)
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("expect *TransportError, got = %T, %v", err, err)
+	}
+}
+
+func TestSameMimeType(t *testing.T) {
+	for _, v := range []struct {
+		A, B string
+		Same bool
+	}{
+		{"image/jpeg", "image/jpeg", true},
+		{"IMAGE/JPG", "image/jpeg", true},
+		{"image/png; charset=binary", "image/png", true},
+		{"image/png", "image/gif", false},
+		{"", "image/gif", false},
+	} {
+		if a, b := sameMimeType(v.A, v.B), v.Same; a != b {
+			t.Fatalf("%s, %s: expect = %v, got = %v", v.A, v.B, b, a)
+		}
+	}
+}
+
+func TestArticle_imageItems(t *testing.T) {
+	article := &Article{Images: []ArticleImage{
+		{Url: "http://a.com/1.jpg", PixelWidth: 10, PixelHeight: 20, Caption: "one"},
+	}}
+	if a, b := article.ImageItems(), []ImageItem{
+		{Url: "http://a.com/1.jpg", PixelWidth: 10, PixelHeight: 20, Caption: "one"},
+	}; !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+}
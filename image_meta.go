@@ -0,0 +1,345 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The faces, ocr and colors fields of the images are returned in several
+// forms, e.g. the faces as "x,y,height,width" strings or as objects, and
+// the colors as a list of hex values in a string or as weighted objects.
+// They are decoded into the typed structures below, the values which can
+// not be recognised are skipped.
+
+// ImageFace is the bounding box of a human face in an image.
+type ImageFace struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ImageFaces is the faces of an image.
+//
+// It is decoded from a list of objects, of "x,y,height,width" strings,
+// or of [x, y, height, width] arrays, or from a string of faces separated
+// by ';' or '|'. It is encoded as a list of objects.
+type ImageFaces []ImageFace
+
+// UnmarshalJSON implements json.Unmarshaler, see ImageFaces.
+func (p *ImageFaces) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var faces ImageFaces
+	var elems []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		elems = v
+	case string:
+		for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == '|' }) {
+			elems = append(elems, s)
+		}
+	case map[string]interface{}:
+		elems = []interface{}{v}
+	}
+	for _, elem := range elems {
+		if face, ok := parseImageFace(elem); ok {
+			faces = append(faces, face)
+		}
+	}
+	*p = faces
+	return nil
+}
+
+func parseImageFace(v interface{}) (face ImageFace, ok bool) {
+	var nums []float64
+	switch v := v.(type) {
+	case string:
+		var fields []interface{}
+		for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			fields = append(fields, s)
+		}
+		nums, ok = parseJsonNumbers(fields)
+	case []interface{}:
+		nums, ok = parseJsonNumbers(v)
+	case map[string]interface{}:
+		x, ok1 := jsonNumberField(v, "x", "left")
+		y, ok2 := jsonNumberField(v, "y", "top")
+		w, ok3 := jsonNumberField(v, "width", "w")
+		h, ok4 := jsonNumberField(v, "height", "h")
+		if ok1 && ok2 && ok3 && ok4 {
+			return ImageFace{int(x), int(y), int(w), int(h)}, true
+		}
+		return face, false
+	}
+	if !ok || len(nums) != 4 {
+		return face, false
+	}
+	// The order of the documentation: x, y, height, width.
+	return ImageFace{X: int(nums[0]), Y: int(nums[1]), Height: int(nums[2]), Width: int(nums[3])}, true
+}
+
+// ImageColor is a dominant color of an image.
+type ImageColor struct {
+	Hex    string  `json:"hex"` // Lowercase "#rrggbb"
+	R      uint8   `json:"r"`
+	G      uint8   `json:"g"`
+	B      uint8   `json:"b"`
+	Weight float64 `json:"weight,omitempty"` // Fraction of the image, 0 if unknown
+}
+
+// ParseImageColor parses a hex color, e.g. "#ff8000", "ff8000" or "#f80".
+func ParseImageColor(s string) (ImageColor, error) {
+	hex := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return ImageColor{}, fmt.Errorf("diffbot: invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return ImageColor{}, fmt.Errorf("diffbot: invalid color %q", s)
+	}
+	return ImageColor{Hex: "#" + hex, R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// ImageColors is the color palette of an image.
+//
+// It is decoded from a string of hex values separated by commas or
+// spaces, or from a list of hex strings, of {"hex": ..., "weight": ...}
+// objects, or of [r, g, b] arrays. It is encoded as a list of objects.
+type ImageColors []ImageColor
+
+// UnmarshalJSON implements json.Unmarshaler, see ImageColors.
+func (p *ImageColors) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var colors ImageColors
+	var elems []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		elems = v
+	case string:
+		for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+			elems = append(elems, s)
+		}
+	case map[string]interface{}:
+		elems = []interface{}{v}
+	}
+	for _, elem := range elems {
+		if color, ok := parseImageColor(elem); ok {
+			colors = append(colors, color)
+		}
+	}
+	*p = colors
+	return nil
+}
+
+func parseImageColor(v interface{}) (color ImageColor, ok bool) {
+	switch v := v.(type) {
+	case string:
+		color, err := ParseImageColor(v)
+		return color, err == nil
+	case []interface{}:
+		nums, ok := parseJsonNumbers(v)
+		if !ok || len(nums) != 3 {
+			return color, false
+		}
+		return rgbImageColor(nums[0], nums[1], nums[2]), true
+	case map[string]interface{}:
+		if s, _ := jsonStringField(v, "hex", "color"); s != "" {
+			if color, err := ParseImageColor(s); err == nil {
+				color.Weight, _ = jsonNumberField(v, "weight", "fraction")
+				return color, true
+			}
+			return color, false
+		}
+		r, ok1 := jsonNumberField(v, "r", "red")
+		g, ok2 := jsonNumberField(v, "g", "green")
+		b, ok3 := jsonNumberField(v, "b", "blue")
+		if ok1 && ok2 && ok3 {
+			color = rgbImageColor(r, g, b)
+			color.Weight, _ = jsonNumberField(v, "weight", "fraction")
+			return color, true
+		}
+	}
+	return color, false
+}
+
+// rgbImageColor returns the color of the components, they are clamped
+// to 0..255.
+func rgbImageColor(r, g, b float64) ImageColor {
+	clamp := func(v float64) uint8 {
+		switch {
+		case v < 0 || math.IsNaN(v):
+			return 0
+		case v > 255:
+			return 255
+		}
+		return uint8(v)
+	}
+	color := ImageColor{R: clamp(r), G: clamp(g), B: clamp(b)}
+	color.Hex = fmt.Sprintf("#%02x%02x%02x", color.R, color.G, color.B)
+	return color
+}
+
+// ImageOcrBlock is a block of the text recognized in an image.
+type ImageOcrBlock struct {
+	Text       string  `json:"text"`
+	X          int     `json:"x,omitempty"`
+	Y          int     `json:"y,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// ImageOcr is the text recognized in an image.
+//
+// It is decoded from a plain string, which is kept in Text without
+// blocks, or from a list of block objects or strings, or from an object
+// with the "text" and "blocks" fields. The Text of the blocks is joined
+// by newlines if it is missing. It is encoded as a plain string if there
+// are no blocks, and omitted from ImageItem if it is empty.
+type ImageOcr struct {
+	Text   string          `json:"text"`
+	Blocks []ImageOcrBlock `json:"blocks,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see ImageOcr.
+func (p *ImageOcr) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var ocr ImageOcr
+	var elems []interface{}
+	switch v := v.(type) {
+	case string:
+		ocr.Text = v
+	case []interface{}:
+		elems = v
+	case map[string]interface{}:
+		if blocks, ok := v["blocks"].([]interface{}); ok {
+			elems = blocks
+			ocr.Text, _ = jsonStringField(v, "text")
+		} else {
+			elems = []interface{}{v}
+		}
+	}
+	for _, elem := range elems {
+		switch elem := elem.(type) {
+		case string:
+			ocr.Blocks = append(ocr.Blocks, ImageOcrBlock{Text: elem})
+		case map[string]interface{}:
+			var block ImageOcrBlock
+			block.Text, _ = jsonStringField(elem, "text")
+			if x, ok := jsonNumberField(elem, "x", "left"); ok {
+				block.X = int(x)
+			}
+			if y, ok := jsonNumberField(elem, "y", "top"); ok {
+				block.Y = int(y)
+			}
+			if w, ok := jsonNumberField(elem, "width", "w"); ok {
+				block.Width = int(w)
+			}
+			if h, ok := jsonNumberField(elem, "height", "h"); ok {
+				block.Height = int(h)
+			}
+			block.Confidence, _ = jsonNumberField(elem, "confidence")
+			ocr.Blocks = append(ocr.Blocks, block)
+		}
+	}
+	if ocr.Text == "" && len(ocr.Blocks) != 0 {
+		texts := make([]string, len(ocr.Blocks))
+		for i, block := range ocr.Blocks {
+			texts[i] = block.Text
+		}
+		ocr.Text = strings.Join(texts, "\n")
+	}
+	*p = ocr
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, see ImageOcr.
+func (p ImageOcr) MarshalJSON() ([]byte, error) {
+	if len(p.Blocks) == 0 {
+		return json.Marshal(p.Text)
+	}
+	type imageOcr ImageOcr // without the methods
+	return json.Marshal(imageOcr(p))
+}
+
+func (p ImageOcr) String() string {
+	return p.Text
+}
+
+// MarshalJSON implements json.Marshaler, the empty Ocr is omitted.
+func (p ImageItem) MarshalJSON() ([]byte, error) {
+	type imageItem ImageItem // without the methods
+	v := struct {
+		imageItem
+		Ocr *ImageOcr `json:"ocr,omitempty"`
+	}{imageItem: imageItem(p)}
+	if p.Ocr.Text != "" || len(p.Ocr.Blocks) != 0 {
+		v.Ocr = &p.Ocr
+	}
+	return json.Marshal(v)
+}
+
+// parseJsonNumbers parses the numbers, or the strings of numbers.
+func parseJsonNumbers(values []interface{}) ([]float64, bool) {
+	nums := make([]float64, len(values))
+	for i, v := range values {
+		n, ok := jsonNumber(v)
+		if !ok {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+func jsonNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// jsonNumberField returns the first number field of names.
+func jsonNumberField(m map[string]interface{}, names ...string) (float64, bool) {
+	for _, name := range names {
+		if v, ok := m[name]; ok {
+			if n, ok := jsonNumber(v); ok {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// jsonStringField returns the first string field of names.
+func jsonStringField(m map[string]interface{}, names ...string) (string, bool) {
+	for _, name := range names {
+		if s, ok := m[name].(string); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
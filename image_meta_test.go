@@ -0,0 +1,180 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestImageFaces_unmarshalJSON(t *testing.T) {
+	face := ImageFaces{{X: 10, Y: 20, Width: 40, Height: 30}}
+	for _, v := range []struct {
+		Json  string
+		Faces ImageFaces
+	}{
+		{`null`, nil},
+		{`[]`, nil},
+		{`["10,20,30,40"]`, face},
+		{`["10 20 30 40"]`, face},
+		{`"10,20,30,40"`, face},
+		{`[[10, 20, 30, 40]]`, face},
+		{`[["10", "20", "30", "40"]]`, face},
+		{`[{"x": 10, "y": 20, "width": 40, "height": 30}]`, face},
+		{`{"left": 10, "top": 20, "w": 40, "h": 30}`, face},
+		{`"10,20,30,40;1,2,3,4"`, ImageFaces{face[0], {X: 1, Y: 2, Width: 4, Height: 3}}},
+		{`["10,20,30", "a,b,c,d", {"x": 1}, 3, "10,20,30,40"]`, face},
+	} {
+		var faces ImageFaces
+		if err := json.Unmarshal([]byte(v.Json), &faces); err != nil {
+			t.Fatalf("%s: %v", v.Json, err)
+		}
+		if a, b := faces, v.Faces; !reflect.DeepEqual(a, b) {
+			t.Fatalf("%s: expect = %v, got = %v", v.Json, b, a)
+		}
+	}
+}
+
+func TestImageColors_unmarshalJSON(t *testing.T) {
+	red := ImageColor{Hex: "#ff0000", R: 255}
+	blue := ImageColor{Hex: "#0000ff", B: 255}
+	for _, v := range []struct {
+		Json   string
+		Colors ImageColors
+	}{
+		{`null`, nil},
+		{`""`, nil},
+		{`"#FF0000,#0000ff"`, ImageColors{red, blue}},
+		{`"ff0000 #00f"`, ImageColors{red, blue}},
+		{`["#ff0000", "#0000ff"]`, ImageColors{red, blue}},
+		{`[[255, 0, 0], [0, 0, 255]]`, ImageColors{red, blue}},
+		{`[{"hex": "#ff0000", "weight": 0.75}, {"color": "0000ff", "fraction": "0.25"}]`, ImageColors{
+			{Hex: "#ff0000", R: 255, Weight: 0.75},
+			{Hex: "#0000ff", B: 255, Weight: 0.25},
+		}},
+		{`[{"r": 255, "g": 0, "b": 0, "weight": 0.5}]`, ImageColors{{Hex: "#ff0000", R: 255, Weight: 0.5}}},
+		{`[[256, 300, -1], {"r": 1000, "g": -20, "b": 255.5}]`, ImageColors{
+			{Hex: "#ffff00", R: 255, G: 255},
+			{Hex: "#ff00ff", R: 255, B: 255},
+		}},
+		{`["#ff00", "#gg0000", [1, 2], {"hex": "x"}, "#ff0000"]`, ImageColors{red}},
+	} {
+		var colors ImageColors
+		if err := json.Unmarshal([]byte(v.Json), &colors); err != nil {
+			t.Fatalf("%s: %v", v.Json, err)
+		}
+		if a, b := colors, v.Colors; !reflect.DeepEqual(a, b) {
+			t.Fatalf("%s: expect = %v, got = %v", v.Json, b, a)
+		}
+	}
+}
+
+func TestParseImageColor(t *testing.T) {
+	color, err := ParseImageColor(" #1A2b3C ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := color, (ImageColor{Hex: "#1a2b3c", R: 0x1a, G: 0x2b, B: 0x3c}); a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	for _, s := range []string{"", "#12345", "#1234567", "#xyzxyz"} {
+		if _, err := ParseImageColor(s); err == nil {
+			t.Fatalf("%q: expect error, got nil", s)
+		}
+	}
+}
+
+func TestImageOcr_unmarshalJSON(t *testing.T) {
+	for _, v := range []struct {
+		Json string
+		Ocr  ImageOcr
+	}{
+		{`"STOP"`, ImageOcr{Text: "STOP"}},
+		{`["STOP", "AHEAD"]`, ImageOcr{
+			Text:   "STOP\nAHEAD",
+			Blocks: []ImageOcrBlock{{Text: "STOP"}, {Text: "AHEAD"}},
+		}},
+		{`[{"text": "STOP", "x": 1, "y": 2, "width": 30, "height": "10", "confidence": 0.9}]`, ImageOcr{
+			Text:   "STOP",
+			Blocks: []ImageOcrBlock{{Text: "STOP", X: 1, Y: 2, Width: 30, Height: 10, Confidence: 0.9}},
+		}},
+		{`{"text": "STOP AHEAD", "blocks": [{"text": "STOP"}, {"text": "AHEAD"}]}`, ImageOcr{
+			Text:   "STOP AHEAD",
+			Blocks: []ImageOcrBlock{{Text: "STOP"}, {Text: "AHEAD"}},
+		}},
+	} {
+		var ocr ImageOcr
+		if err := json.Unmarshal([]byte(v.Json), &ocr); err != nil {
+			t.Fatalf("%s: %v", v.Json, err)
+		}
+		if a, b := ocr, v.Ocr; !reflect.DeepEqual(a, b) {
+			t.Fatalf("%s: expect = %v, got = %v", v.Json, b, a)
+		}
+	}
+}
+
+func TestImageItem_typedFields(t *testing.T) {
+	var img Image
+	if err := img.ParseJson([]byte(testJsonDataImageTypedFields)); err != nil {
+		t.Fatal(err)
+	}
+	if len(img.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", img.Warnings)
+	}
+	item := img.Images[0]
+	if a, b := item.Faces, (ImageFaces{{X: 10, Y: 20, Width: 40, Height: 30}}); !reflect.DeepEqual(a, b) {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := item.Ocr.String(), "STOP"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := len(item.Colors), 2; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := img.Images[1].Ocr.String(), "A"; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+	if a, b := img.Images[1].Ocr.Blocks[0].X, 1; a != b {
+		t.Fatalf("expect = %v, got = %v", b, a)
+	}
+
+	// The empty Ocr is omitted.
+	data, err := json.Marshal(ImageItem{Url: "http://example.com/c.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(`"ocr"`)) {
+		t.Fatalf("unexpected ocr field: %s", data)
+	}
+
+	// The typed fields are encoded as the canonical forms.
+	var img2 Image
+	if err := img2.ParseJson([]byte(img.String())); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(img.Images, img2.Images) {
+		t.Fatalf("not equal, expect = \n%v, got = \n%v", img.Images, img2.Images)
+	}
+}
+
+const testJsonDataImageTypedFields = `{
+	"title": "Typed",
+	"images": [
+		{
+			"url": "http://example.com/a.jpg",
+			"faces": ["10,20,30,40"],
+			"ocr": "STOP",
+			"colors": "#ff0000,#0000ff"
+		},
+		{
+			"url": "http://example.com/b.jpg",
+			"faces": [{"x": 1, "y": 2, "width": 3, "height": 4}],
+			"ocr": [{"text": "A", "x": 1}],
+			"colors": [{"hex": "#00ff00", "weight": 1}]
+		}
+	]
+}`
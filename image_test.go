@@ -12,7 +12,7 @@ import (
 
 func TestImage_type(t *testing.T) {
 	var a Image
-	a.Images = append(a.Images, imageImageType{})
+	a.Images = append(a.Images, ImageItem{})
 	_ = a
 }
 
@@ -36,7 +36,7 @@ func TestImage_parseJson(t *testing.T) {
 		t.Fatalf("not equal, expect = \n%q, got = \n%q", testGoldenImage, result1)
 	}
 	for i := 0; i < len(images) && i < len(testGoldenImageImages); i++ {
-		if !reflect.DeepEqual(testGoldenImageImages[i], imageImageType(images[i])) {
+		if !reflect.DeepEqual(testGoldenImageImages[i], ImageItem(images[i])) {
 			t.Fatalf("%d: not equal, expect = \n%q, got = \n%q", i, testGoldenImageImages[i], images[i])
 		}
 	}
@@ -52,7 +52,7 @@ var testGoldenImage = Image{
 	QueryString: "",
 	Links:       nil,
 }
-var testGoldenImageImages = []imageImageType{
+var testGoldenImageImages = []ImageItem{
 	{
 		Url:           "http://www.statesymbolsusa.org/IMAGES/rose_usda-web.jpg",
 		AnchorUrl:     "",
@@ -85,8 +85,8 @@ var testGoldenImageImages = []imageImageType{
 			"[Adobe Jpeg] Color Transform - YCbCr",
 		},
 		Faces:  nil,
-		Ocr:    "",
-		Colors: "",
+		Ocr:    ImageOcr{},
+		Colors: nil,
 		XPath:  "/HTML[1]/BODY[1]/DIV[1]/TABLE[3]/TBODY[1]/TR[2]/TD[4]/DIV[1]/DIV[1]/H6[1]/SPAN[1]/A[1]/IMG[1]",
 	},
 	{
@@ -121,8 +121,8 @@ var testGoldenImageImages = []imageImageType{
 			"[Adobe Jpeg] Color Transform - YCbCr",
 		},
 		Faces:  nil,
-		Ocr:    "",
-		Colors: "",
+		Ocr:    ImageOcr{},
+		Colors: nil,
 		XPath:  "/HTML[1]/BODY[1]/DIV[1]/TABLE[3]/TBODY[1]/TR[2]/TD[4]/DIV[1]/DIV[1]/TABLE[1]/TBODY[1]/TR[1]/TD[1]/DIV[1]/H6[1]/SPAN[1]/A[1]/IMG[1]",
 	},
 }
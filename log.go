@@ -0,0 +1,157 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// The Diffbot client emits the following records to Options.Logger:
+//
+//	+-------+-------------------------+------------------------------------------+
+//	| LEVEL | MESSAGE                 | ATTRIBUTES                               |
+//	+-------+-------------------------+------------------------------------------+
+//	| DEBUG | diffbot request         | method, url                              |
+//	| DEBUG | diffbot response        | method, url, status, bytes, duration     |
+//	| DEBUG | diffbot response body   | method, body, truncated                  |
+//	| WARN  | diffbot request failed  | method, url, status, error_code, error   |
+//	| INFO  | diffbot retry           | method, url, attempt, delay, error       |
+//	| DEBUG | diffbot cache           | method, url, hit                         |
+//	| WARN  | diffbot decode failed   | method, error                            |
+//	+-------+-------------------------+------------------------------------------+
+//
+// The token in url is always redacted. The response body is only logged
+// if Options.LogResponseBody is greater than 0, truncated to that length.
+// The retry and cache records are emitted by the retry and cache
+// middlewares with LogRetry and LogCacheEvent, the retries of the
+// rate limited articles of FrontpageFollower are logged too.
+
+type callLogContextKey struct{}
+
+// callLog is the logger of a Diffbot call, it is kept in the context of
+// the request for LogRetry and LogCacheEvent.
+type callLog struct {
+	opt    *Options
+	method string
+}
+
+func contextWithCallLog(ctx context.Context, opt *Options, method string) context.Context {
+	return context.WithValue(ctx, callLogContextKey{}, &callLog{opt: opt, method: method})
+}
+
+// LogRetry logs a retry of the Diffbot call of req to its Options.Logger,
+// attempt is the number of the retry from 1, and err is the failure of
+// the previous attempt. It is used by the retry middlewares.
+func LogRetry(req *http.Request, attempt int, delay time.Duration, err error) {
+	if call, ok := req.Context().Value(callLogContextKey{}).(*callLog); ok {
+		call.opt.logRetry(req.Context(), call.method, req.URL.String(), attempt, delay, err)
+	}
+}
+
+// LogCacheEvent logs a cache hit or miss of the Diffbot call of req to its
+// Options.Logger. It is used by the cache middlewares.
+func LogCacheEvent(req *http.Request, hit bool) {
+	if call, ok := req.Context().Value(callLogContextKey{}).(*callLog); ok {
+		call.opt.logger().LogAttrs(req.Context(), slog.LevelDebug, "diffbot cache",
+			slog.String("method", call.method),
+			slog.String("url", redactToken(req.URL.String())),
+			slog.Bool("hit", hit),
+		)
+	}
+}
+
+func (p *Options) logger() *slog.Logger {
+	if p == nil || p.Logger == nil {
+		return discardLogger
+	}
+	return p.Logger
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+func (p *Options) logResponseBody() int {
+	if p == nil {
+		return 0
+	}
+	return p.LogResponseBody
+}
+
+func (p *Options) logRequest(ctx context.Context, method string, req *http.Request) {
+	p.logger().LogAttrs(ctx, slog.LevelDebug, "diffbot request",
+		slog.String("method", method),
+		slog.String("url", redactToken(req.URL.String())),
+	)
+}
+
+func (p *Options) logResponse(ctx context.Context, method string, req *http.Request, resp *http.Response, body []byte, duration time.Duration, err error) {
+	logger := p.logger()
+	url := redactToken(req.URL.String())
+
+	if resp != nil {
+		logger.LogAttrs(ctx, slog.LevelDebug, "diffbot response",
+			slog.String("method", method),
+			slog.String("url", url),
+			slog.Int("status", resp.StatusCode),
+			slog.Int("bytes", len(body)),
+			slog.Duration("duration", duration),
+		)
+		if n := p.logResponseBody(); n > 0 && len(body) != 0 {
+			truncated := len(body) > n
+			if truncated {
+				body = body[:n]
+			}
+			logger.LogAttrs(ctx, slog.LevelDebug, "diffbot response body",
+				slog.String("method", method),
+				slog.String("body", string(body)),
+				slog.Bool("truncated", truncated),
+			)
+		}
+	}
+
+	if err != nil {
+		attrs := []slog.Attr{
+			slog.String("method", method),
+			slog.String("url", url),
+		}
+		if resp != nil {
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		}
+		if apiErr, ok := err.(*Error); ok {
+			attrs = append(attrs, slog.Int("error_code", apiErr.ErrCode))
+		}
+		attrs = append(attrs, slog.String("error", redactToken(err.Error())))
+		logger.LogAttrs(ctx, slog.LevelWarn, "diffbot request failed", attrs...)
+	}
+}
+
+func (p *Options) logRetry(ctx context.Context, method, url string, attempt int, delay time.Duration, err error) {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("url", redactToken(url)),
+		slog.Int("attempt", attempt),
+		slog.Duration("delay", delay),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactToken(err.Error())))
+	}
+	p.logger().LogAttrs(ctx, slog.LevelInfo, "diffbot retry", attrs...)
+}
+
+func (p *Options) logDecodeError(ctx context.Context, method string, err error) {
+	p.logger().LogAttrs(ctx, slog.LevelWarn, "diffbot decode failed",
+		slog.String("method", method),
+		slog.String("error", err.Error()),
+	)
+}
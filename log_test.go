@@ -0,0 +1,187 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("token") {
+		case "secret-token":
+			fmt.Fprint(w, `{"type":"article","title":"Diffbot's New Product API"}`)
+		case "bad-json":
+			fmt.Fprint(w, `{"type":`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Not authorized API token.","errorCode":401}`)
+		}
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	opt := &Options{
+		Logger:          slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		LogResponseBody: 16,
+	}
+
+	if _, err := DiffbotServer(ts.URL, "article", "secret-token", "http://example.com/", opt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DiffbotServer(ts.URL, "article", "bad-secret-token", "http://example.com/", opt); err == nil {
+		t.Fatal("expect error, got nil")
+	}
+
+	opt.Client = testRedirectDoer(ts.URL)
+	if _, err := ParseArticle("bad-json", "http://example.com/", opt); err == nil {
+		t.Fatal("expect error, got nil")
+	}
+	log := buf.String()
+
+	if strings.Contains(log, "secret-token") {
+		t.Fatalf("token not redacted:\n%s", log)
+	}
+	for _, s := range []string{
+		`msg="diffbot request" method=article url="` + ts.URL + `/article?token=REDACTED&url=`,
+		`msg="diffbot response" method=article`,
+		`status=200`,
+		`msg="diffbot response body" method=article body="{\"type\":\"article" truncated=true`,
+		`level=WARN msg="diffbot request failed" method=article`,
+		`status=401 error_code=401`,
+		`level=WARN msg="diffbot decode failed" method=article error="diffbot: article: decode: unexpected end of JSON input"`,
+	} {
+		if !strings.Contains(log, s) {
+			t.Fatalf("missing %q in:\n%s", s, log)
+		}
+	}
+}
+
+func TestLogger_nilOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"article","title":"Diffbot's New Product API"}`)
+	}))
+	defer ts.Close()
+
+	if _, err := DiffbotServer(ts.URL, "article", "secret-token", "http://example.com/", nil); err != nil {
+		t.Fatal(err)
+	}
+	var opt *Options
+	opt.logResponse(context.Background(), "article", httptest.NewRequest("GET", ts.URL, nil),
+		&http.Response{StatusCode: http.StatusOK}, []byte("{}"), 0, nil)
+}
+
+func TestRedactToken(t *testing.T) {
+	for i, v := range []struct {
+		s      string
+		expect string
+	}{
+		{
+			s:      "http://api.diffbot.com/v2/article?token=abc&url=http%3A%2F%2Fexample.com",
+			expect: "http://api.diffbot.com/v2/article?token=REDACTED&url=http%3A%2F%2Fexample.com",
+		},
+		{
+			s:      `Get "http://api.diffbot.com/v2/article?token=abc": EOF`,
+			expect: `Get "http://api.diffbot.com/v2/article?token=REDACTED": EOF`,
+		},
+		{
+			s:      "http://api.diffbot.com/v2/article?url=http%3A%2F%2Fexample.com%3Ftoken%3Dabc",
+			expect: "http://api.diffbot.com/v2/article?url=http%3A%2F%2Fexample.com%3Ftoken%3Dabc",
+		},
+	} {
+		if s := redactToken(v.s); s != v.expect {
+			t.Fatalf("%d: expect = %q, got = %q", i, v.expect, s)
+		}
+	}
+}
+
+func TestLogger_retryAndCache(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"article"}`)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	cache := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			LogCacheEvent(req, false)
+			LogRetry(req, 1, time.Second, errors.New("connection reset"))
+			return next.Do(req)
+		})
+	}
+	opt := &Options{
+		Logger:      slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Middlewares: []Middleware{cache},
+	}
+	if _, err := DiffbotServer(ts.URL, "article", "secret-token", "http://example.com/", opt); err != nil {
+		t.Fatal(err)
+	}
+	log := buf.String()
+	if strings.Contains(log, "secret-token") {
+		t.Fatalf("token not redacted:\n%s", log)
+	}
+	for _, s := range []string{
+		`level=DEBUG msg="diffbot cache" method=article url="` + ts.URL + `/article?token=REDACTED&url=`,
+		`hit=false`,
+		`level=INFO msg="diffbot retry" method=article url="` + ts.URL + `/article?token=REDACTED&url=`,
+		`attempt=1 delay=1s error="connection reset"`,
+	} {
+		if !strings.Contains(log, s) {
+			t.Fatalf("missing %q in:\n%s", s, log)
+		}
+	}
+
+	// The requests of other clients are not logged.
+	LogCacheEvent(httptest.NewRequest("GET", ts.URL, nil), true)
+}
+
+type testLogContextKey struct{}
+
+// testContextHandler records the messages with the test value of the
+// context of each record.
+type testContextHandler struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (p *testContextHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (p *testContextHandler) WithAttrs([]slog.Attr) slog.Handler       { return p }
+func (p *testContextHandler) WithGroup(string) slog.Handler            { return p }
+
+func (p *testContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, fmt.Sprintf("%s: %v", r.Message, ctx.Value(testLogContextKey{})))
+	return nil
+}
+
+func TestLogger_context(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":`)
+	}))
+	defer ts.Close()
+
+	handler := &testContextHandler{}
+	opt := &Options{Logger: slog.New(handler), Client: testRedirectDoer(ts.URL)}
+	ctx := context.WithValue(context.Background(), testLogContextKey{}, "call-1")
+	if _, err := ParseArticleContext(ctx, "token", "http://example.com/", opt); err == nil {
+		t.Fatal("expect error, got nil")
+	}
+	for _, s := range []string{"diffbot request: call-1", "diffbot decode failed: call-1"} {
+		if !strings.Contains(strings.Join(handler.records, "\n"), s) {
+			t.Fatalf("missing %q in: %v", s, handler.records)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsBuckets is the default latency histogram buckets, in seconds.
+var DefaultMetricsBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics collects the call volume, latency, error codes and
+// bytes transferred of the Diffbot calls by method.
+//
+// Metrics is exported in the Prometheus text format, so it can be
+// scraped without depending on the Prometheus client library:
+//
+//	metrics := diffbot.NewMetrics()
+//	http.Handle("/metrics", metrics)
+//
+//	opt := &diffbot.Options{Metrics: metrics}
+//	article, err := diffbot.ParseArticle(token, url, opt)
+//
+// The exported metrics are:
+//
+//	diffbot_requests_total{method}             counter
+//	diffbot_errors_total{method,code}          counter
+//	diffbot_response_bytes_total{method}       counter
+//	diffbot_request_duration_seconds{method}   histogram
+//
+// The code label is the Error.ErrCode for API errors, or "transport"
+// for the other errors.
+type Metrics struct {
+	buckets []float64
+	mu      sync.Mutex
+	methods map[string]*methodMetrics
+}
+
+type methodMetrics struct {
+	requests uint64
+	bytes    uint64
+	errors   map[string]uint64
+	counts   []uint64 // histogram buckets, not cumulative
+	sum      float64
+}
+
+// NewMetrics creates a Metrics with the latency histogram buckets (in seconds).
+// If no buckets are given, the DefaultMetricsBuckets is used.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets: buckets,
+		methods: make(map[string]*methodMetrics),
+	}
+}
+
+// Observe records a Diffbot call.
+func (p *Metrics) Observe(method string, duration time.Duration, bytes int, err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m, ok := p.methods[method]
+	if !ok {
+		m = &methodMetrics{
+			errors: make(map[string]uint64),
+			counts: make([]uint64, len(p.buckets)+1),
+		}
+		p.methods[method] = m
+	}
+
+	seconds := duration.Seconds()
+	m.requests++
+	m.bytes += uint64(bytes)
+	m.sum += seconds
+	m.counts[sort.SearchFloat64s(p.buckets, seconds)]++
+	if err != nil {
+		m.errors[metricsErrorCode(err)]++
+	}
+}
+
+func metricsErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return strconv.Itoa(apiErr.ErrCode)
+	}
+	return "transport"
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (p *Metrics) WriteTo(w io.Writer) (n int64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	methods := make([]string, 0, len(p.methods))
+	for method := range p.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	bw := bufio.NewWriter(w)
+	cw := &countWriter{w: bw}
+
+	fmt.Fprintln(cw, "# HELP diffbot_requests_total Total number of Diffbot API calls.")
+	fmt.Fprintln(cw, "# TYPE diffbot_requests_total counter")
+	for _, method := range methods {
+		fmt.Fprintf(cw, "diffbot_requests_total{method=%q} %d\n", method, p.methods[method].requests)
+	}
+
+	fmt.Fprintln(cw, "# HELP diffbot_errors_total Total number of failed Diffbot API calls.")
+	fmt.Fprintln(cw, "# TYPE diffbot_errors_total counter")
+	for _, method := range methods {
+		m := p.methods[method]
+		codes := make([]string, 0, len(m.errors))
+		for code := range m.errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(cw, "diffbot_errors_total{method=%q,code=%q} %d\n", method, code, m.errors[code])
+		}
+	}
+
+	fmt.Fprintln(cw, "# HELP diffbot_response_bytes_total Total bytes of Diffbot API responses.")
+	fmt.Fprintln(cw, "# TYPE diffbot_response_bytes_total counter")
+	for _, method := range methods {
+		fmt.Fprintf(cw, "diffbot_response_bytes_total{method=%q} %d\n", method, p.methods[method].bytes)
+	}
+
+	fmt.Fprintln(cw, "# HELP diffbot_request_duration_seconds Latency of Diffbot API calls.")
+	fmt.Fprintln(cw, "# TYPE diffbot_request_duration_seconds histogram")
+	for _, method := range methods {
+		m := p.methods[method]
+		var count uint64
+		for i, le := range p.buckets {
+			count += m.counts[i]
+			fmt.Fprintf(cw, "diffbot_request_duration_seconds_bucket{method=%q,le=%q} %d\n",
+				method, strconv.FormatFloat(le, 'g', -1, 64), count,
+			)
+		}
+		count += m.counts[len(p.buckets)]
+		fmt.Fprintf(cw, "diffbot_request_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", method, count)
+		fmt.Fprintf(cw, "diffbot_request_duration_seconds_sum{method=%q} %s\n",
+			method, strconv.FormatFloat(m.sum, 'g', -1, 64),
+		)
+		fmt.Fprintf(cw, "diffbot_request_duration_seconds_count{method=%q} %d\n", method, count)
+	}
+
+	if err = bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (p *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (p *countWriter) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	p.err = err
+	return n, err
+}
@@ -0,0 +1,73 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Not authorized API token.","errorCode":401}`)
+			return
+		}
+		fmt.Fprint(w, `{"type":"article"}`)
+	}))
+	defer ts.Close()
+
+	metrics := NewMetrics(0.5, 0.1)
+	opt := &Options{Metrics: metrics}
+	DiffbotServer(ts.URL, "article", "token", "http://example.com/", opt)
+	DiffbotServer(ts.URL, "article", "token", "http://example.com/", opt)
+	DiffbotServer(ts.URL, "article", "bad-token", "http://example.com/", opt)
+	metrics.Observe("image", time.Second, 10, fmt.Errorf("EOF"))
+
+	handler := httptest.NewServer(metrics)
+	defer handler.Close()
+	resp, err := http.Get(handler.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	for _, line := range []string{
+		`# TYPE diffbot_requests_total counter`,
+		`diffbot_requests_total{method="article"} 3`,
+		`diffbot_requests_total{method="image"} 1`,
+		`diffbot_errors_total{method="article",code="401"} 1`,
+		`diffbot_errors_total{method="image",code="transport"} 1`,
+		fmt.Sprintf(`diffbot_response_bytes_total{method="article"} %d`, 2*len(`{"type":"article"}`)+len(`{"error":"Not authorized API token.","errorCode":401}`)),
+		`diffbot_response_bytes_total{method="image"} 10`,
+		`# TYPE diffbot_request_duration_seconds histogram`,
+		`diffbot_request_duration_seconds_bucket{method="article",le="+Inf"} 3`,
+		`diffbot_request_duration_seconds_count{method="article"} 3`,
+		`diffbot_request_duration_seconds_bucket{method="image",le="0.1"} 0`,
+		`diffbot_request_duration_seconds_bucket{method="image",le="0.5"} 0`,
+		`diffbot_request_duration_seconds_bucket{method="image",le="+Inf"} 1`,
+		`diffbot_request_duration_seconds_sum{method="image"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, text)
+		}
+	}
+}
+
+func TestMetrics_nil(t *testing.T) {
+	var metrics *Metrics
+	metrics.Observe("article", time.Second, 0, nil)
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"net/http"
+)
+
+// Doer sends a HTTP request and returns a HTTP response.
+//
+// The *http.Client type implements the Doer interface.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer to inject behaviour around the HTTP round trip,
+// e.g. auth headers, logging, metrics, tracing or fault injection.
+//
+// Example:
+//
+//	opt := &diffbot.Options{
+//		Middlewares: []diffbot.Middleware{
+//			func(next diffbot.Doer) diffbot.Doer {
+//				return diffbot.DoerFunc(func(req *http.Request) (*http.Response, error) {
+//					req.Header.Set("X-Request-Id", newRequestId())
+//					return next.Do(req)
+//				})
+//			},
+//		},
+//	}
+type Middleware func(next Doer) Doer
+
+// Chain wraps the Doer with the middlewares.
+//
+// The first middleware is the outermost one, it sees the request first
+// and the response last.
+func Chain(doer Doer, middlewares ...Middleware) Doer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			doer = middlewares[i](doer)
+		}
+	}
+	return doer
+}
+
+// Hooks holds the lifecycle callbacks of a Diffbot call.
+//
+// Any nil callback is ignored.
+type Hooks struct {
+	// BeforeRequest is called before the request is sent.
+	BeforeRequest func(req *http.Request)
+
+	// AfterResponse is called after the response body has been read.
+	AfterResponse func(req *http.Request, resp *http.Response, body []byte)
+
+	// OnError is called when the call fails, include the API errors.
+	OnError func(req *http.Request, err error)
+}
+
+func (p *Hooks) beforeRequest(req *http.Request) {
+	if p != nil && p.BeforeRequest != nil {
+		p.BeforeRequest(req)
+	}
+}
+
+func (p *Hooks) afterResponse(req *http.Request, resp *http.Response, body []byte) {
+	if p != nil && p.AfterResponse != nil {
+		p.AfterResponse(req, resp, body)
+	}
+}
+
+func (p *Hooks) onError(req *http.Request, err error) {
+	if p != nil && p.OnError != nil {
+		p.OnError(req, err)
+	}
+}
@@ -5,6 +5,7 @@
 package diffbot
 
 import (
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -19,6 +20,7 @@ type Options struct {
 	Timeout                time.Duration
 	Callback               string
 	FrontpageAll           string
+	FrontpageFormat        string // "xml" (default) or "json"
 	ClassifierMode         string
 	ClassifierStats        string
 	BulkNotifyEmail        string
@@ -38,6 +40,19 @@ type Options struct {
 	BatchMethod            string
 	BatchRelativeUrl       string
 	CustomHeader           http.Header
+	Client                 Doer         // Default is http.DefaultClient.
+	Middlewares            []Middleware // Wraps the Client, see Chain.
+	Hooks                  *Hooks
+	Metrics                *Metrics // Disabled if nil.
+	Tracer                 Tracer   // Disabled if nil.
+	Logger                 *slog.Logger
+	LogResponseBody        int  // Max length of the response body in debug logs, 0 is disabled.
+	StrictFields           bool // Report the unknown JSON fields as errors, see UnknownFields.
+	ArticleAllPages        bool // Fetch and stitch all pages of an article, see ParseArticleContext.
+	ArticleMaxPages        int  // Default is 20.
+	ArticlePageConcurrency int  // Default is 4.
+
+	ImageAlbumMaxPages int // Default is 20, see ImageAlbumIterator.
 }
 
 // MethodParamString return string as the url params.
@@ -72,6 +87,9 @@ func (p *Options) MethodParamString(method string) string {
 		if p.FrontpageAll != "" {
 			s = append(s, ("&all=" + p.FrontpageAll)...)
 		}
+		if p.FrontpageFormat != "" {
+			s = append(s, ("&format=" + p.FrontpageFormat)...)
+		}
 		return string(s)
 
 	case "analyze":
@@ -165,3 +183,35 @@ func (p *Options) MethodParamString(method string) string {
 
 	return ""
 }
+
+func (p *Options) doer() Doer {
+	if p == nil {
+		return http.DefaultClient
+	}
+	var doer Doer = http.DefaultClient
+	if p.Client != nil {
+		doer = p.Client
+	}
+	return Chain(doer, p.Middlewares...)
+}
+
+func (p *Options) metrics() *Metrics {
+	if p == nil {
+		return nil
+	}
+	return p.Metrics
+}
+
+func (p *Options) tracer() Tracer {
+	if p == nil {
+		return nil
+	}
+	return p.Tracer
+}
+
+func (p *Options) hooks() *Hooks {
+	if p == nil {
+		return nil
+	}
+	return p.Hooks
+}
@@ -73,6 +73,14 @@ var testOptionsList = []struct {
 		},
 		str: "&timeout=5000&all=*",
 	},
+	{
+		method: "frontpage",
+		opt: Options{
+			FrontpageAll:    "1",
+			FrontpageFormat: "json",
+		},
+		str: "&all=1&format=json",
+	},
 
 	// case "analyze":
 	{
@@ -5,6 +5,7 @@
 package diffbot
 
 import (
+	"context"
 	"encoding/json"
 )
 
@@ -12,68 +13,39 @@ import (
 //
 // See http://diffbot.com/dev/docs/product/
 type Product struct {
-	Url         string                 `json:"url"`
-	ResolvedUrl string                 `json:"resolved_url"`
-	Meta        map[string]interface{} `json:"meta,omitempty"`        // Returned with fields.
-	QueryString string                 `json:"querystring,omitempty"` // Returned with fields.
-	Links       []string               `json:"links,omitempty"`       // Returned with fields.
-	Breadcrumb  []string               `json:"breadcrumb"`
-	Products    []struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Brand       string `json:"brand,omitempty"` // Returned with fields.
-		Medias      []struct {
-			Type    string `json:"type"`
-			Link    string `json:"link"`
-			Height  int    `json:"height"`
-			Width   int    `json:"width"`
-			Caption string `json:"caption"`
-			Primary string `json:"primary"`
-			XPath   string `json:"xpath"`
-		} `json:"media"`
-		OfferPrice     string `json:"offerPrice"`
-		RegularPrice   string `json:"regularPrice"`
-		SaveAmount     string `json:"saveAmount"`
-		ShippingAmount string `json:"shippingAmount"`
-		ProductId      string `json:"productId"`
-		Upc            string `json:"upc"`
-		PrefixCode     string `json:"prefixCode"`
-		ProductOrigin  string `json:"productOrigin"`
-		Isbn           string `json:"isbn"`
-		Sku            string `json:"sku,omitempty"` // Returned with fields.
-		Mpn            string `json:"mpn,omitempty"` // Returned with fields.
-	} `json:"products"`
+	Url         string                     `json:"url"`
+	ResolvedUrl string                     `json:"resolved_url"`
+	Meta        map[string]interface{}     `json:"meta,omitempty"`        // Returned with fields.
+	QueryString string                     `json:"querystring,omitempty"` // Returned with fields.
+	Links       []string                   `json:"links,omitempty"`       // Returned with fields.
+	Breadcrumb  []string                   `json:"breadcrumb"`
+	Products    []ProductItem              `json:"products"`
+	Warnings    []DecodeWarning            `json:"-"` // Mismatched fields, see ParseJson.
+	Raw         json.RawMessage            `json:"-"` // The JSON data, see ParseJson.
+	Extra       map[string]json.RawMessage `json:"-"` // Unrecognised JSON fields, see ParseJson.
 }
 
-// type of Product.Products[?]
-type productProductType struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Brand       string `json:"brand,omitempty"` // Returned with fields.
-	Medias      []struct {
-		Type    string `json:"type"`
-		Link    string `json:"link"`
-		Height  int    `json:"height"`
-		Width   int    `json:"width"`
-		Caption string `json:"caption"`
-		Primary string `json:"primary"`
-		XPath   string `json:"xpath"`
-	} `json:"media"`
-	OfferPrice     string `json:"offerPrice"`
-	RegularPrice   string `json:"regularPrice"`
-	SaveAmount     string `json:"saveAmount"`
-	ShippingAmount string `json:"shippingAmount"`
-	ProductId      string `json:"productId"`
-	Upc            string `json:"upc"`
-	PrefixCode     string `json:"prefixCode"`
-	ProductOrigin  string `json:"productOrigin"`
-	Isbn           string `json:"isbn"`
-	Sku            string `json:"sku,omitempty"` // Returned with fields.
-	Mpn            string `json:"mpn,omitempty"` // Returned with fields.
+// ProductItem represents a product of the Product result.
+type ProductItem struct {
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	Brand          string         `json:"brand,omitempty"` // Returned with fields.
+	Medias         []ProductMedia `json:"media"`
+	OfferPrice     string         `json:"offerPrice"`
+	RegularPrice   string         `json:"regularPrice"`
+	SaveAmount     string         `json:"saveAmount"`
+	ShippingAmount string         `json:"shippingAmount"`
+	ProductId      string         `json:"productId"`
+	Upc            string         `json:"upc"`
+	PrefixCode     string         `json:"prefixCode"`
+	ProductOrigin  string         `json:"productOrigin"`
+	Isbn           string         `json:"isbn"`
+	Sku            string         `json:"sku,omitempty"` // Returned with fields.
+	Mpn            string         `json:"mpn,omitempty"` // Returned with fields.
 }
 
-// type of Product.Products[?].Medias[?]
-type productProductMediaType struct {
+// ProductMedia represents a media of the ProductItem.
+type ProductMedia struct {
 	Type    string `json:"type"`
 	Link    string `json:"link"`
 	Height  int    `json:"height"`
@@ -227,17 +199,68 @@ type productProductMediaType struct {
 //	  "url": "http://store.livrada.com/collections/all/products/before-i-go-to-sleep"
 //	}
 func ParseProduct(token, url string, opt *Options) (*Product, error) {
-	body, err := Diffbot("product", token, url, opt)
+	return ParseProductContext(context.Background(), token, url, opt)
+}
+
+// ParseProductContext like ParseProduct function, but carries a context.
+func ParseProductContext(ctx context.Context, token, url string, opt *Options) (*Product, error) {
+	body, err := DiffbotContext(ctx, "product", token, url, opt)
 	if err != nil {
 		return nil, err
 	}
 	var result Product
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := opt.parseJson(ctx, "product", body, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
+// ParseJson parses the JSON-encoded Product data, see DecodeWarning. The
+// unknown fields are errors only in ParseProduct with Options.StrictFields.
+func (p *Product) ParseJson(data []byte) (err error) {
+	p.Warnings, p.Raw, p.Extra, err = decodeResultJson(data, p)
+	return err
+}
+
+// PrimaryMedia returns the primary media, or the first media if no media
+// is marked as primary. It returns nil if the product has no medias.
+func (p *ProductItem) PrimaryMedia() *ProductMedia {
+	for i := range p.Medias {
+		if p.Medias[i].IsPrimary() {
+			return &p.Medias[i]
+		}
+	}
+	if len(p.Medias) != 0 {
+		return &p.Medias[0]
+	}
+	return nil
+}
+
+// OfferPriceMoney parses the OfferPrice field, see ParseMoney.
+func (p *ProductItem) OfferPriceMoney() (Money, error) {
+	return ParseMoney(p.OfferPrice)
+}
+
+// RegularPriceMoney parses the RegularPrice field, see ParseMoney.
+func (p *ProductItem) RegularPriceMoney() (Money, error) {
+	return ParseMoney(p.RegularPrice)
+}
+
+// SaveAmountMoney parses the SaveAmount field, see ParseMoney.
+func (p *ProductItem) SaveAmountMoney() (Money, error) {
+	return ParseMoney(p.SaveAmount)
+}
+
+// ShippingAmountMoney parses the ShippingAmount field, see ParseMoney.
+func (p *ProductItem) ShippingAmountMoney() (Money, error) {
+	return ParseMoney(p.ShippingAmount)
+}
+
+// IsPrimary parses the Primary field.
+func (p *ProductMedia) IsPrimary() bool {
+	return ParseBool(p.Primary)
+}
+
 func (p *Product) String() string {
 	d, _ := json.Marshal(p)
 	return string(d)
@@ -12,8 +12,8 @@ import (
 
 func Product_type(t *testing.T) {
 	var a Product
-	a.Products = append(a.Products, productProductType{})
-	a.Products[0].Medias = append(a.Products[0].Medias, productProductMediaType{})
+	a.Products = append(a.Products, ProductItem{})
+	a.Products[0].Medias = append(a.Products[0].Medias, ProductMedia{})
 	_ = a
 }
 
@@ -35,10 +35,24 @@ func TestProduct_parseJson(t *testing.T) {
 	}
 }
 
+func TestProductItem_helpers(t *testing.T) {
+	var result Product
+	if err := result.ParseJson([]byte(testJsonDataProduct)); err != nil {
+		t.Fatal(err)
+	}
+	item := &result.Products[0]
+	if a, b := item.Medias[0].Link, item.PrimaryMedia().Link; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if (&ProductItem{}).PrimaryMedia() != nil {
+		t.Fatalf("expect nil")
+	}
+}
+
 var testGoldenProduct = func() (result Product) {
 	result.Url = "http://store.livrada.com/collections/all/products/before-i-go-to-sleep"
-	result.Products = append(result.Products, productProductType{})
-	result.Products[0].Medias = append(result.Products[0].Medias, productProductMediaType{})
+	result.Products = append(result.Products, ProductItem{})
+	result.Products[0].Medias = append(result.Products[0].Medias, ProductMedia{})
 
 	result.Products[0].Title = "Before I Go To Sleep"
 	result.Products[0].Description = "Memories define us. So what if you lost yours every time you went to sleep? Your name, your identity, your past, even the people you love -- all forgotten overnight. And the one person you trust may be telling you only half the story. Before I Go To Sleep is a disturbing psychological thriller in which an amnesiac desperately tries to uncover the truth about who she is and who she can trust."
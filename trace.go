@@ -0,0 +1,146 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"sync"
+)
+
+// Attribute keys of the Diffbot call spans.
+const (
+	AttrMethod     = "diffbot.method"            // Diffbot method, e.g. "article"
+	AttrTargetHost = "diffbot.target.host"       // Host of the processed URL
+	AttrStatusCode = "http.response.status_code" // HTTP status code of the API response
+	AttrErrCode    = "diffbot.error_code"        // Error.ErrCode of the API error
+	AttrRetries    = "diffbot.retries"           // Set by retry middlewares
+	AttrCacheHit   = "diffbot.cache_hit"         // Set by cache middlewares
+)
+
+// Tracer starts the spans of the Diffbot calls.
+//
+// It is a small subset of the OpenTelemetry trace API, so an
+// OpenTelemetry tracer can be adapted without this package
+// depending on it:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, diffbot.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+// The returned context is attached to the HTTP request, so the
+// middlewares and the http.Client transport see the span. The span is
+// stored in it with ContextWithSpan, the Tracer does not need to.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced Diffbot call.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key/value pair attached to a Span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of ctx holding the span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span of the current Diffbot call.
+//
+// Middlewares use it to annotate the call, e.g. with the AttrRetries
+// or AttrCacheHit attributes. If ctx holds no span, a no-op span is returned.
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+func (noopSpan) RecordError(err error)            {}
+func (noopSpan) End()                             {}
+
+// SpanRecorder is an in-memory Tracer, useful for testing.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span recorded by SpanRecorder.
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Errors     []error
+	Ended      bool
+
+	mu *sync.Mutex
+}
+
+// Start starts a RecordedSpan. If ctx holds a RecordedSpan, it is the parent.
+func (p *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		mu:         &p.mu,
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(*RecordedSpan); ok {
+		span.Parent = parent
+	}
+
+	p.mu.Lock()
+	p.spans = append(p.spans, span)
+	p.mu.Unlock()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans returns the recorded spans, in start order.
+func (p *SpanRecorder) Spans() []*RecordedSpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*RecordedSpan(nil), p.spans...)
+}
+
+// Reset drops the recorded spans.
+func (p *SpanRecorder) Reset() {
+	p.mu.Lock()
+	p.spans = nil
+	p.mu.Unlock()
+}
+
+func (p *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, attr := range attrs {
+		p.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (p *RecordedSpan) RecordError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Errors = append(p.Errors, err)
+}
+
+func (p *RecordedSpan) End() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Ended = true
+}
@@ -0,0 +1,191 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Not authorized API token.","errorCode":401}`)
+			return
+		}
+		fmt.Fprint(w, `{"type":"article"}`)
+	}))
+	defer ts.Close()
+
+	recorder := &SpanRecorder{}
+	cache := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			SpanFromContext(req.Context()).SetAttributes(Attribute{Key: AttrCacheHit, Value: false})
+			return next.Do(req)
+		})
+	}
+	opt := &Options{
+		Tracer:      recorder,
+		Middlewares: []Middleware{cache},
+	}
+
+	ctx, parent := recorder.Start(context.Background(), "ingest")
+	if _, err := DiffbotServerContext(ctx, ts.URL, "article", "token", "http://blog.diffbot.com/a", opt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DiffbotServerContext(ctx, ts.URL, "article", "bad-token", "http://blog.diffbot.com/a", opt); err == nil {
+		t.Fatal("expect error, got nil")
+	}
+	parent.End()
+
+	spans := recorder.Spans()
+	if a, b := 3, len(spans); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	for i, span := range spans[1:] {
+		if a, b := "diffbot.article", span.Name; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+		if span.Parent != parent {
+			t.Fatalf("%d: context not propagated", i)
+		}
+		if !span.Ended {
+			t.Fatalf("%d: span not ended", i)
+		}
+		if a, b := "article", span.Attributes[AttrMethod]; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+		if a, b := "blog.diffbot.com", span.Attributes[AttrTargetHost]; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+		if a, b := false, span.Attributes[AttrCacheHit]; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+	}
+
+	if a, b := 200, spans[1].Attributes[AttrStatusCode]; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if len(spans[1].Errors) != 0 {
+		t.Fatalf("expect no errors, got = %v", spans[1].Errors)
+	}
+	if a, b := 401, spans[2].Attributes[AttrStatusCode]; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 401, spans[2].Attributes[AttrErrCode]; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 1, len(spans[2].Errors); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+// testOpaqueTracer is a Tracer which does not store the span in ctx,
+// like an OpenTelemetry adapter.
+type testOpaqueTracer struct {
+	recorder *SpanRecorder
+}
+
+func (p testOpaqueTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	_, span := p.recorder.Start(ctx, name)
+	return ctx, span
+}
+
+func TestTracer_opaque(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"article"}`)
+	}))
+	defer ts.Close()
+
+	recorder := &SpanRecorder{}
+	cache := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			SpanFromContext(req.Context()).SetAttributes(Attribute{Key: AttrCacheHit, Value: true})
+			return next.Do(req)
+		})
+	}
+	opt := &Options{
+		Tracer:      testOpaqueTracer{recorder},
+		Middlewares: []Middleware{cache},
+	}
+	if _, err := DiffbotServerContext(context.Background(), ts.URL, "article", "token", "http://blog.diffbot.com/a", opt); err != nil {
+		t.Fatal(err)
+	}
+
+	// The middlewares see the span, though the Tracer does not store it.
+	spans := recorder.Spans()
+	if a, b := 1, len(spans); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := true, spans[0].Attributes[AttrCacheHit]; a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestTracer_disabled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"Not authorized API token.","errorCode":401}`)
+	}))
+	defer ts.Close()
+
+	recorder := &SpanRecorder{}
+	ctx, parent := recorder.Start(context.Background(), "ingest")
+	if _, err := DiffbotServerContext(ctx, ts.URL, "article", "bad-token", "http://blog.diffbot.com/a", &Options{}); err == nil {
+		t.Fatal("expect error, got nil")
+	}
+	parent.End()
+
+	// The span of the caller is not annotated if the tracing is disabled.
+	if a, b := 1, len(recorder.Spans()); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if span := recorder.Spans()[0]; len(span.Attributes) != 0 || len(span.Errors) != 0 {
+		t.Fatalf("unexpected annotations: %v, %v", span.Attributes, span.Errors)
+	}
+}
+
+func TestTracer_parseContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"`+strings.TrimPrefix(r.URL.Path, "/")+`"}`)
+	}))
+	defer ts.Close()
+
+	recorder := &SpanRecorder{}
+	opt := &Options{Tracer: recorder, Client: testRedirectDoer(ts.URL)}
+	ctx, parent := recorder.Start(context.Background(), "ingest")
+	if _, err := ParseProductContext(ctx, "token", "http://example.com/p", opt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseClassificationContext(ctx, "token", "http://example.com/p", opt); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Spans()
+	if a, b := 3, len(spans); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	for i, name := range []string{"diffbot.product", "diffbot.analyze"} {
+		if a, b := name, spans[i+1].Name; a != b {
+			t.Fatalf("%d: expect = %v, got = %v", i, a, b)
+		}
+		if spans[i+1].Parent != parent {
+			t.Fatalf("%d: context not propagated", i)
+		}
+	}
+}
+
+func TestSpanFromContext_noop(t *testing.T) {
+	span := SpanFromContext(context.Background())
+	span.SetAttributes(Attribute{Key: AttrRetries, Value: 1})
+	span.RecordError(fmt.Errorf("EOF"))
+	span.End()
+}
@@ -5,6 +5,7 @@
 package diffbot
 
 import (
+	"regexp"
 	"strconv"
 )
 
@@ -17,3 +18,10 @@ func atof(s string) float64 {
 	v, _ := strconv.ParseFloat(s, 64)
 	return v
 }
+
+var tokenParamRegexp = regexp.MustCompile(`([?&]token=)[^&\s"]*`)
+
+// redactToken hides the token param of the request url in s.
+func redactToken(s string) string {
+	return tokenParamRegexp.ReplaceAllString(s, "${1}REDACTED")
+}
@@ -0,0 +1,187 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Diffbot returns most of the values as strings, e.g. "true", "3",
+// "$19.99" or "Wed, 31 Jul 2013 08:00:00 GMT". The raw strings are
+// kept in the result types, and the helpers below parse them.
+
+// dateLayouts is the layouts tried by ParseDate, in order.
+var dateLayouts = []string{
+	time.RFC1123,
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC850,
+	time.RFC822,
+	time.RFC822Z,
+	time.ANSIC,
+	time.UnixDate,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// ParseDate parses a date string returned by Diffbot,
+// e.g. "Wed, 31 Jul 2013 08:00:00 GMT".
+//
+// The RFC 1123, RFC 3339, RFC 850, RFC 822, ANSI C and some
+// common human readable layouts are supported.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("diffbot: invalid date %q", s)
+}
+
+// ParseBool parses a bool string returned by Diffbot, e.g. "true".
+// It returns false for the invalid strings.
+func ParseBool(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1", "yes", "y":
+		return true
+	}
+	return false
+}
+
+// ParseInt parses an integer string returned by Diffbot, e.g. "3".
+// The thousands separators are ignored.
+func ParseInt(s string) (int, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("diffbot: invalid int %q", s)
+	}
+	return v, nil
+}
+
+// Money represents a price, e.g. "$19.99".
+type Money struct {
+	Amount   float64
+	Currency string // ISO 4217 code, e.g. "USD", empty if unknown
+	Raw      string // The string returned by Diffbot
+}
+
+func (p Money) String() string {
+	if p.Currency == "" {
+		return strconv.FormatFloat(p.Amount, 'f', 2, 64)
+	}
+	return p.Currency + " " + strconv.FormatFloat(p.Amount, 'f', 2, 64)
+}
+
+// moneySymbols maps the currency symbols to the ISO 4217 codes.
+var moneySymbols = []struct {
+	symbol string
+	code   string
+}{
+	// The longer symbols first.
+	{"US$", "USD"},
+	{"C$", "CAD"},
+	{"A$", "AUD"},
+	{"R$", "BRL"},
+	{"HK$", "HKD"},
+	{"$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"₹", "INR"},
+	{"₩", "KRW"},
+	{"₽", "RUB"},
+}
+
+// ParseMoney parses a price string returned by Diffbot,
+// e.g. "$19.99", "19,99 €", "USD 1,299.00".
+//
+// The currency is detected from the symbol or the ISO 4217 code.
+// Both "1,299.00" and "1.299,00" are accepted.
+func ParseMoney(s string) (Money, error) {
+	m := Money{Raw: s}
+	rest := strings.TrimSpace(s)
+
+	for _, v := range moneySymbols {
+		if strings.Contains(rest, v.symbol) {
+			m.Currency = v.code
+			rest = strings.Replace(rest, v.symbol, "", 1)
+			break
+		}
+	}
+	if m.Currency == "" {
+		for _, field := range strings.Fields(rest) {
+			if len(field) == 3 && strings.ToUpper(field) == field && isLetters(field) {
+				m.Currency = field
+				rest = strings.Replace(rest, field, "", 1)
+				break
+			}
+		}
+	}
+
+	amount, ok := normalizeAmount(strings.TrimSpace(rest))
+	if !ok {
+		return m, fmt.Errorf("diffbot: invalid money %q", s)
+	}
+	v, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return m, fmt.Errorf("diffbot: invalid money %q", s)
+	}
+	m.Amount = v
+	return m, nil
+}
+
+// normalizeAmount converts "1,299.00" or "1.299,00" to "1299.00".
+func normalizeAmount(s string) (string, bool) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "", false
+	}
+
+	comma, dot := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	switch {
+	case comma >= 0 && dot >= 0:
+		if comma > dot {
+			s = strings.Replace(s, ".", "", -1)
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.Replace(s, ",", "", -1)
+		}
+	case comma >= 0:
+		if strings.Count(s, ",") == 1 && len(s)-comma-1 != 3 {
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.Replace(s, ",", "", -1)
+		}
+	}
+	return s, true
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+// Copyright 2014 <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diffbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	expect := time.Date(2013, 7, 31, 8, 0, 0, 0, time.UTC)
+	for i, s := range []string{
+		"Wed, 31 Jul 2013 08:00:00 GMT",
+		"Wed, 31 Jul 2013 08:00:00 +0000",
+		"2013-07-31T08:00:00Z",
+		"2013-07-31T08:00:00",
+		"2013-07-31 08:00:00",
+		" Wednesday, 31-Jul-13 08:00:00 UTC ",
+	} {
+		got, err := ParseDate(s)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !got.Equal(expect) {
+			t.Fatalf("%d: expect = %v, got = %v", i, expect, got)
+		}
+	}
+
+	for i, s := range []string{"July 31, 2013", "Jul 31, 2013", "31 July 2013", "2013-07-31"} {
+		got, err := ParseDate(s)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if y, m, d := got.Date(); y != 2013 || m != time.July || d != 31 {
+			t.Fatalf("%d: got = %v", i, got)
+		}
+	}
+
+	if _, err := ParseDate("yesterday"); err == nil {
+		t.Fatalf("expect error, got nil")
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	for s, expect := range map[string]bool{
+		"true": true, "TRUE": true, "1": true, " yes ": true,
+		"false": false, "0": false, "": false, "abc": false,
+	} {
+		if got := ParseBool(s); got != expect {
+			t.Fatalf("%q: expect = %v, got = %v", s, expect, got)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	for s, expect := range map[string]int{"3": 3, " 12 ": 12, "1,024": 1024, "-1": -1} {
+		got, err := ParseInt(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if got != expect {
+			t.Fatalf("%q: expect = %v, got = %v", s, expect, got)
+		}
+	}
+	if _, err := ParseInt("3.5"); err == nil {
+		t.Fatalf("expect error, got nil")
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	for i, v := range []struct {
+		s        string
+		amount   float64
+		currency string
+	}{
+		{"$19.99", 19.99, "USD"},
+		{"$7.99", 7.99, "USD"},
+		{"19,99 €", 19.99, "EUR"},
+		{"€1.299,00", 1299, "EUR"},
+		{"£1,299.00", 1299, "GBP"},
+		{"USD 1,299", 1299, "USD"},
+		{"1 299,50 SEK", 1299.5, "SEK"},
+		{"¥1,000", 1000, "JPY"},
+		{"C$ 5", 5, "CAD"},
+		{"12.50", 12.5, ""},
+	} {
+		m, err := ParseMoney(v.s)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if m.Amount != v.amount || m.Currency != v.currency || m.Raw != v.s {
+			t.Fatalf("%d: expect = %v %v, got = %+v", i, v.currency, v.amount, m)
+		}
+	}
+
+	for i, s := range []string{"", "$", "free", "USD abc"} {
+		if _, err := ParseMoney(s); err == nil {
+			t.Fatalf("%d: expect error, got nil", i)
+		}
+	}
+
+	if a, b := "USD 19.90", (Money{Amount: 19.9, Currency: "USD"}).String(); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestArticle_values(t *testing.T) {
+	a := Article{Date: "Wed, 31 Jul 2013 08:00:00 GMT", NumPages: "3"}
+	if d, err := a.DateTime(); err != nil || d.Year() != 2013 {
+		t.Fatalf("got = %v, %v", d, err)
+	}
+	if a, b := 3, a.PageCount(); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+	if a, b := 1, (&Article{}).PageCount(); a != b {
+		t.Fatalf("expect = %v, got = %v", a, b)
+	}
+}
+
+func TestElement_values(t *testing.T) {
+	item := FrontpageItem{PubDate: "Thu, 17 May 2012 20:06:26 GMT"}
+	if d, err := item.PubDateTime(); err != nil || d.Year() != 2012 {
+		t.Fatalf("got = %v, %v", d, err)
+	}
+
+	product := ProductItem{OfferPrice: "$7.99", RegularPrice: "$9.99", SaveAmount: "$2.00"}
+	if m, err := product.OfferPriceMoney(); err != nil || m.Amount != 7.99 || m.Currency != "USD" {
+		t.Fatalf("got = %v, %v", m, err)
+	}
+	if m, err := product.RegularPriceMoney(); err != nil || m.Amount != 9.99 {
+		t.Fatalf("got = %v, %v", m, err)
+	}
+	if m, err := product.SaveAmountMoney(); err != nil || m.Amount != 2 {
+		t.Fatalf("got = %v, %v", m, err)
+	}
+	if _, err := product.ShippingAmountMoney(); err == nil {
+		t.Fatalf("expect error, got nil")
+	}
+
+	if !(&ArticleImage{Primary: "true"}).IsPrimary() || (&ArticleVideo{}).IsPrimary() {
+		t.Fatalf("invalid primary flags")
+	}
+	if !(&ProductMedia{Primary: "TRUE"}).IsPrimary() {
+		t.Fatalf("invalid primary flag")
+	}
+}